@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/database"
+	"go-api-test1/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const promoteAdminUsage = `usage: go-api-test1 [flags] promote-admin <email>
+
+Grants the admin role to the registered user with the given email.`
+
+// runPromoteAdminCommand implements the `promote-admin` command
+func runPromoteAdminCommand(cfg *config.Config, args []string, logger *slog.Logger) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one email\n%s", promoteAdminUsage)
+	}
+
+	db, err := database.Initialize(cfg.Database, logger)
+	if err != nil {
+		return err
+	}
+	user, err := promoteAdmin(context.Background(), db, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("granted admin role to %s (user %d); it applies to tokens issued from now on\n", user.Email, user.ID)
+	return nil
+}
+
+// promoteAdmin grants the admin role to the user with the given email
+func promoteAdmin(ctx context.Context, db *gorm.DB, email string) (*models.User, error) {
+	var user models.User
+	if err := db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no user with email %q", email)
+		}
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -31,7 +31,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all assets",
+                "description": "Get a page of assets",
                 "consumes": [
                     "application/json"
                 ],
@@ -42,14 +42,76 @@ const docTemplate = `{
                     "assets"
                 ],
                 "summary": "Get all assets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page's pagination.next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys, prefix with - for descending: id, name, symbol, type, created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by asset type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by symbol",
+                        "name": "symbol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by active flag",
+                        "name": "is_active",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Asset"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.ListResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Asset"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "401": {
@@ -92,6 +154,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.CreateAssetRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -107,6 +175,115 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/assets/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create assets in bulk from a CSV file (with a header row) or a JSON array of asset objects. Every row is validated like POST /assets and the response reports the outcome of each row. With upsert, rows whose symbol already exists update that asset instead of failing. With dry_run nothing is written. By default the import is atomic: if any row fails, no asset is written and the response is 422. With atomic=false valid rows are written and failed rows are reported.",
+                "consumes": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "assets"
+                ],
+                "summary": "Import assets",
+                "parameters": [
+                    {
+                        "description": "Assets as a JSON array, or CSV with columns name, symbol, type, description, price, quantity_scale, price_scale",
+                        "name": "assets",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CreateAssetRequest"
+                            }
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate and report without writing (default false)",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Update assets whose symbol already exists (default false)",
+                        "name": "upsert",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Write nothing if any row fails (default true)",
+                        "name": "atomic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AssetImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.AssetImportReport"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -176,7 +353,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a specific asset by its ID",
+                "description": "Update a specific asset by its ID. A price change is appended to the asset's price history.",
                 "consumes": [
                     "application/json"
                 ],
@@ -289,9 +466,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "Authenticate a user and return a JWT token",
+        "/assets/{id}/candles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get OHLC candles of an asset built from its price history and completed buys and sells. Volume is the traded amount. Intervals without prices or trades are omitted.",
                 "consumes": [
                     "application/json"
                 ],
@@ -299,25 +481,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "assets"
                 ],
-                "summary": "Login user",
+                "summary": "Get asset candles",
                 "parameters": [
                     {
-                        "description": "User login credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "Asset ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Candle interval: 1m, 1h or 1d (default 1h)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start time, RFC 3339 or YYYY-MM-DD (default 100 intervals before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End time, exclusive, RFC 3339 or YYYY-MM-DD (default now)",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.AuthResponse"
+                            "$ref": "#/definitions/models.CandlesResponse"
                         }
                     },
                     "400": {
@@ -326,8 +524,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -341,9 +539,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Register a new user account",
+        "/assets/{id}/prices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of an asset's price history, newest first by default",
                 "consumes": [
                     "application/json"
                 ],
@@ -351,25 +554,80 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "assets"
                 ],
-                "summary": "Register a new user",
+                "summary": "Get asset price history",
                 "parameters": [
                     {
-                        "description": "User registration data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.RegisterRequest"
-                        }
+                        "type": "integer",
+                        "description": "Asset ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only prices recorded at or after this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only prices recorded before this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by source",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page's pagination.next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys, prefix with - for descending: id, recorded_at",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.AuthResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.ListResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.AssetPrice"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -378,8 +636,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -393,14 +651,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/transactions": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get a list of all transactions",
+        "/auth/login": {
+            "post": {
+                "description": "Authenticate a user and return a JWT token",
                 "consumes": [
                     "application/json"
                 ],
@@ -408,17 +661,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "User login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
                 ],
-                "summary": "Get all transactions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Transaction"
-                            }
+                            "$ref": "#/definitions/models.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "401": {
@@ -434,14 +701,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/logout": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new transaction",
+                "description": "Revoke the current access token. If a refresh token is supplied, every refresh token issued from the same login is revoked too.",
                 "consumes": [
                     "application/json"
                 ],
@@ -449,25 +718,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "auth"
                 ],
-                "summary": "Create transaction",
+                "summary": "Logout user",
                 "parameters": [
                     {
-                        "description": "Transaction data",
-                        "name": "transaction",
+                        "description": "Refresh token to revoke",
+                        "name": "token",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateTransactionRequest"
+                            "$ref": "#/definitions/models.LogoutRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Transaction"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -476,6 +747,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -485,14 +762,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/transactions/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get a specific transaction by its ID",
+        "/auth/refresh": {
+            "post": {
+                "description": "Rotate a refresh token and return a new access token. Replaying a refresh token that was already used revokes every token issued from the same login.",
                 "consumes": [
                     "application/json"
                 ],
@@ -500,23 +772,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "auth"
                 ],
-                "summary": "Get transaction by ID",
+                "summary": "Refresh access token",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Transaction ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Transaction"
+                            "$ref": "#/definitions/models.AuthResponse"
                         }
                     },
                     "400": {
@@ -525,8 +799,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -538,14 +812,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Update a specific transaction by its ID",
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Register a new user account",
                 "consumes": [
                     "application/json"
                 ],
@@ -553,32 +824,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "auth"
                 ],
-                "summary": "Update transaction",
+                "summary": "Register a new user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Transaction ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Transaction update data",
-                        "name": "transaction",
+                        "description": "User registration data",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateTransactionRequest"
+                            "$ref": "#/definitions/models.RegisterRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Transaction"
+                            "$ref": "#/definitions/models.AuthResponse"
                         }
                     },
                     "400": {
@@ -587,8 +857,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -600,14 +876,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/price-feed": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a specific transaction by its ID",
+                "description": "Get the configuration and ingestion metrics of this instance's price feed (admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -615,42 +893,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
-                ],
-                "summary": "Delete transaction",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Transaction ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "assets"
                 ],
+                "summary": "Get price feed status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/pricefeed.Status"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -658,14 +918,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
+        "/transactions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all users",
+                "description": "Get a list of transactions. Non-admins only see their own transactions and transfers they received; transfers carry a direction relative to the caller.",
                 "consumes": [
                     "application/json"
                 ],
@@ -673,17 +933,97 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "transactions"
+                ],
+                "summary": "Get all transactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page's pagination.next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys, prefix with - for descending: id, created_at, updated_at, type, status, asset_id (default -created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by asset",
+                        "name": "asset_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by owner",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions created at or after this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions created before this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.ListResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Transaction"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "401": {
@@ -699,16 +1039,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users/{id}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a specific user by their ID",
+                "description": "Create a new transaction. Transfers require a recipient, by ID or username, who is credited when the transfer completes.",
                 "consumes": [
                     "application/json"
                 ],
@@ -716,23 +1054,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "transactions"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Create transaction",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Transaction data",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTransactionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of this request safe; the first response is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Transaction"
                         }
                     },
                     "400": {
@@ -741,8 +1087,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -754,47 +1106,80 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/transactions/export": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a specific user by their ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Export the transactions the caller can see as CSV, JSON Lines or an OFX investment statement. Takes the same filters and sort as listing but is not paginated; rows are streamed from the database. OFX exports only contain completed transactions.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/x-ofx"
                 ],
                 "tags": [
-                    "users"
+                    "transactions"
                 ],
-                "summary": "Update user",
+                "summary": "Export transactions",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default), jsonl or ofx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys, prefix with - for descending: id, created_at, updated_at, type, status, asset_id (default -created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by asset",
+                        "name": "asset_id",
+                        "in": "query"
                     },
                     {
-                        "description": "User update data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateUserRequest"
-                        }
+                        "type": "integer",
+                        "description": "Filter by owner",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions created at or after this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions created before this time (RFC 3339 or YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -803,8 +1188,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -816,14 +1201,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/transactions/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a specific user by their ID",
+                "description": "Get a specific transaction by its ID. Recipients can view transfers sent to them; other users' transactions are reported as not found for non-admins.",
                 "consumes": [
                     "application/json"
                 ],
@@ -831,13 +1218,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "transactions"
                 ],
-                "summary": "Delete user",
+                "summary": "Get transaction by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Transaction ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -847,10 +1234,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Transaction"
                         }
                     },
                     "400": {
@@ -872,27 +1256,923 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "models.Asset": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string",
-                    "example": "2023-01-01T00:00:00Z"
-                },
-                "description": {
-                    "type": "string",
-                    "example": "Digital currency"
-                },
-                "id": {
-                    "type": "integer",
-                    "example": 1
-                },
-                "is_active": {
-                    "type": "boolean",
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a specific transaction by its ID. Type, amount and price can only be changed while the transaction is pending; status changes follow the transaction state machine. Other users' transactions are reported as not found for non-admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Update transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transaction update data",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateTransactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a specific transaction by its ID. Other users' transactions are reported as not found for non-admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Delete transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel a pending transaction and release any balance it reserved. Other users' transactions are reported as not found for non-admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Cancel transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the change",
+                        "name": "action",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionActionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mark a pending transaction as completed and apply it to the user's holdings. Requires the operator or admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Complete transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the change",
+                        "name": "action",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionActionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}/fail": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mark a pending transaction as failed and release any balance it reserved. Requires the operator or admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Fail transaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the change",
+                        "name": "action",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionActionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every status change of a transaction, oldest first, including who made it. Recipients can view the history of transfers sent to them; other users' transactions are reported as not found for non-admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction status history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TransactionStatusChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get all users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from a previous page's pagination.next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys, prefix with - for descending: id, username, email, created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by active flag",
+                        "name": "is_active",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.ListResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.User"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a specific user by their ID. Non-admins can only access their own record.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a specific user by their ID. Non-admins can only update their own record.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User update data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a specific user by their ID. Non-admins can only delete their own record.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/holdings": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the per-asset balances of a user, including the amount reserved by pending sells and transfers. Non-admins can only access their own holdings.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user holdings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Holding"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/portfolio": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Value a user's positions and compute average cost and realized and unrealized P\u0026L per asset from their completed transactions. Non-admins can only access their own portfolio.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user portfolio",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cost-basis method: fifo, lifo or average (default fifo)",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Snapshot time, RFC 3339 or YYYY-MM-DD for the end of that day (UTC); values at the last price observed by then",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Portfolio"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/reports/capital-gains": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Match a user's sells with their tax lots and report the gains realized in a year, classified as short-term or long-term (held more than one year). Non-admins can only access their own report.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get capital gains report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tax year (default current year, UTC)",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lot matching method: fifo or lifo (default fifo)",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CapitalGainsReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.Asset": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Digital currency"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "is_active": {
+                    "type": "boolean",
                     "example": true
                 },
                 "name": {
@@ -900,8 +2180,28 @@ const docTemplate = `{
                     "example": "Bitcoin"
                 },
                 "price": {
-                    "type": "number",
-                    "example": 50000
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "price_scale": {
+                    "description": "Decimal places of prices",
+                    "type": "integer",
+                    "example": 2
+                },
+                "price_stale": {
+                    "description": "Set by the price feed once Price is too old",
+                    "type": "boolean",
+                    "example": false
+                },
+                "price_updated_at": {
+                    "description": "When Price was last set or confirmed",
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "quantity_scale": {
+                    "description": "Decimal places of amounts",
+                    "type": "integer",
+                    "example": 8
                 },
                 "symbol": {
                     "type": "string",
@@ -917,15 +2217,312 @@ const docTemplate = `{
                 }
             }
         },
+        "models.AssetImportReport": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "atomic": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AssetImportRow"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.AssetImportSummary"
+                },
+                "upsert": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.AssetImportRow": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "create, update, unchanged, error",
+                    "type": "string",
+                    "example": "update"
+                },
+                "asset_id": {
+                    "description": "Omitted for errors and dry-run creates",
+                    "type": "integer",
+                    "example": 1
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "price",
+                        "description"
+                    ]
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "price is required"
+                    ]
+                },
+                "row": {
+                    "description": "1-based row number; CSV rows count the header",
+                    "type": "integer",
+                    "example": 2
+                },
+                "symbol": {
+                    "type": "string",
+                    "example": "BTC"
+                }
+            }
+        },
+        "models.AssetImportSummary": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "unchanged": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "updated": {
+                    "type": "integer",
+                    "example": 15
+                }
+            }
+        },
+        "models.AssetPrice": {
+            "type": "object",
+            "properties": {
+                "asset_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "price": {
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "recorded_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "source": {
+                    "description": "initial, manual, feed, import",
+                    "type": "string",
+                    "example": "manual"
+                }
+            }
+        },
         "models.AuthResponse": {
             "type": "object",
             "properties": {
-                "token": {
+                "expires_in": {
+                    "description": "Access token lifetime in seconds",
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "q1w2e3r4t5y6..."
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                }
+            }
+        },
+        "models.Candle": {
+            "type": "object",
+            "properties": {
+                "close": {
+                    "type": "string",
+                    "example": "50500.00"
+                },
+                "high": {
+                    "type": "string",
+                    "example": "51000.00"
+                },
+                "low": {
+                    "type": "string",
+                    "example": "49500.00"
+                },
+                "open": {
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "time": {
+                    "description": "Start of the interval",
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "trades": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "volume": {
+                    "description": "Traded amount",
+                    "type": "string",
+                    "example": "12.5"
+                }
+            }
+        },
+        "models.CandlesResponse": {
+            "type": "object",
+            "properties": {
+                "asset_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "candles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Candle"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "1h"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2023-01-02T00:00:00Z"
+                }
+            }
+        },
+        "models.CapitalGain": {
+            "type": "object",
+            "properties": {
+                "acquired_at": {
+                    "type": "string",
+                    "example": "2022-03-01T10:00:00Z"
+                },
+                "asset_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "buy_transaction_id": {
+                    "description": "Null when the sell exceeded the known lots",
+                    "type": "integer",
+                    "example": 3
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "example": "20000.00"
+                },
+                "gain": {
+                    "description": "Negative for a loss",
+                    "type": "string",
+                    "example": "7500.00"
+                },
+                "proceeds": {
+                    "type": "string",
+                    "example": "27500.00"
+                },
+                "quantity": {
+                    "type": "string",
+                    "example": "0.5"
+                },
+                "sell_transaction_id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "sold_at": {
+                    "type": "string",
+                    "example": "2023-06-01T10:00:00Z"
+                },
+                "symbol": {
+                    "type": "string",
+                    "example": "BTC"
+                },
+                "term": {
+                    "description": "short, long",
+                    "type": "string",
+                    "example": "long"
+                }
+            }
+        },
+        "models.CapitalGainsReport": {
+            "type": "object",
+            "properties": {
+                "gains": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CapitalGain"
+                    }
+                },
+                "long_term": {
+                    "$ref": "#/definitions/models.CapitalGainsSummary"
+                },
+                "method": {
+                    "description": "fifo, lifo",
+                    "type": "string",
+                    "example": "fifo"
+                },
+                "short_term": {
+                    "$ref": "#/definitions/models.CapitalGainsSummary"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.CapitalGainsSummary"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2023
+                }
+            }
+        },
+        "models.CapitalGainsSummary": {
+            "type": "object",
+            "properties": {
+                "cost_basis": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "20000.00"
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
+                "gain": {
+                    "type": "string",
+                    "example": "7500.00"
+                },
+                "proceeds": {
+                    "type": "string",
+                    "example": "27500.00"
                 }
             }
         },
@@ -947,9 +2544,21 @@ const docTemplate = `{
                     "example": "Bitcoin"
                 },
                 "price": {
-                    "type": "number",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "50000.00"
+                },
+                "price_scale": {
+                    "type": "integer",
+                    "maximum": 18,
+                    "minimum": 0,
+                    "example": 2
+                },
+                "quantity_scale": {
+                    "type": "integer",
+                    "maximum": 18,
                     "minimum": 0,
-                    "example": 50000
+                    "example": 8
                 },
                 "symbol": {
                     "type": "string",
@@ -971,9 +2580,9 @@ const docTemplate = `{
             ],
             "properties": {
                 "amount": {
-                    "type": "number",
-                    "minimum": 0,
-                    "example": 0.5
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "0.5"
                 },
                 "asset_id": {
                     "type": "integer",
@@ -984,9 +2593,19 @@ const docTemplate = `{
                     "example": "Buying Bitcoin"
                 },
                 "price": {
-                    "type": "number",
-                    "minimum": 0,
-                    "example": 50000
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "50000.00"
+                },
+                "recipient_id": {
+                    "description": "Transfers: recipient by ID...",
+                    "type": "integer",
+                    "example": 2
+                },
+                "recipient_username": {
+                    "description": "...or by username",
+                    "type": "string",
+                    "example": "janedoe"
                 },
                 "type": {
                     "type": "string",
@@ -1009,6 +2628,71 @@ const docTemplate = `{
                 "message": {
                     "type": "string",
                     "example": "The request body is invalid"
+                },
+                "request_id": {
+                    "description": "Quote when reporting a problem",
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                },
+                "trace_id": {
+                    "description": "Set when the request is traced",
+                    "type": "string",
+                    "example": "0af7651916cd43dd8448eb211c80319c"
+                }
+            }
+        },
+        "models.Holding": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Asset"
+                        }
+                    ]
+                },
+                "asset_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "available": {
+                    "description": "Quantity - Reserved",
+                    "type": "string",
+                    "example": "1.0"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "quantity": {
+                    "type": "string",
+                    "example": "1.5"
+                },
+                "reserved": {
+                    "type": "string",
+                    "example": "0.5"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.ListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
                 }
             }
         },
@@ -1019,6 +2703,10 @@ const docTemplate = `{
                 "password"
             ],
             "properties": {
+                "device_name": {
+                    "type": "string",
+                    "example": "iPhone 15"
+                },
                 "email": {
                     "type": "string",
                     "example": "user@example.com"
@@ -1029,6 +2717,142 @@ const docTemplate = `{
                 }
             }
         },
+        "models.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "q1w2e3r4t5y6..."
+                }
+            }
+        },
+        "models.Pagination": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "next": {
+                    "type": "string",
+                    "example": "/api/v1/assets?cursor=eyJzIjoiaWQiLCJ2IjpbNTBdfQ\u0026limit=50"
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJ2IjpbNTBdfQ"
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "models.Portfolio": {
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "example": "25000.00"
+                },
+                "market_value": {
+                    "type": "string",
+                    "example": "27500.00"
+                },
+                "method": {
+                    "description": "fifo, lifo, average",
+                    "type": "string",
+                    "example": "fifo"
+                },
+                "positions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PortfolioPosition"
+                    }
+                },
+                "realized_pnl": {
+                    "type": "string",
+                    "example": "1200.00"
+                },
+                "unrealized_pnl": {
+                    "type": "string",
+                    "example": "2500.00"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.PortfolioPosition": {
+            "type": "object",
+            "properties": {
+                "asset_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "average_cost": {
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "example": "25000.00"
+                },
+                "market_value": {
+                    "type": "string",
+                    "example": "27500.00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Bitcoin"
+                },
+                "price": {
+                    "type": "string",
+                    "example": "55000.00"
+                },
+                "price_at": {
+                    "description": "When Price was observed",
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "quantity": {
+                    "type": "string",
+                    "example": "0.5"
+                },
+                "realized_pnl": {
+                    "type": "string",
+                    "example": "1200.00"
+                },
+                "symbol": {
+                    "type": "string",
+                    "example": "BTC"
+                },
+                "unrealized_pnl": {
+                    "type": "string",
+                    "example": "2500.00"
+                }
+            }
+        },
+        "models.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "q1w2e3r4t5y6..."
+                }
+            }
+        },
         "models.RegisterRequest": {
             "type": "object",
             "required": [
@@ -1066,8 +2890,8 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number",
-                    "example": 0.5
+                    "type": "string",
+                    "example": "0.5"
                 },
                 "asset": {
                     "$ref": "#/definitions/models.Asset"
@@ -1084,13 +2908,23 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Buying Bitcoin"
                 },
+                "direction": {
+                    "description": "Transfers only: incoming, outgoing",
+                    "type": "string",
+                    "example": "outgoing"
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
                 },
                 "price": {
-                    "type": "number",
-                    "example": 50000
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "recipient_id": {
+                    "description": "Transfers only",
+                    "type": "integer",
+                    "example": 2
                 },
                 "status": {
                     "description": "pending, completed, failed, cancelled",
@@ -1098,8 +2932,8 @@ const docTemplate = `{
                     "example": "completed"
                 },
                 "total_value": {
-                    "type": "number",
-                    "example": 25000
+                    "type": "string",
+                    "example": "25000.00"
                 },
                 "type": {
                     "description": "buy, sell, transfer",
@@ -1124,6 +2958,48 @@ const docTemplate = `{
                 }
             }
         },
+        "models.TransactionActionRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "Order expired"
+                }
+            }
+        },
+        "models.TransactionStatusChange": {
+            "type": "object",
+            "properties": {
+                "changed_by_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "from_status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Settled by exchange"
+                },
+                "to_status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "transaction_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
         "models.UpdateAssetRequest": {
             "type": "object",
             "properties": {
@@ -1140,8 +3016,20 @@ const docTemplate = `{
                     "example": "Bitcoin"
                 },
                 "price": {
-                    "type": "number",
-                    "example": 50000
+                    "type": "string",
+                    "example": "50000.00"
+                },
+                "price_scale": {
+                    "type": "integer",
+                    "maximum": 18,
+                    "minimum": 0,
+                    "example": 2
+                },
+                "quantity_scale": {
+                    "type": "integer",
+                    "maximum": 18,
+                    "minimum": 0,
+                    "example": 8
                 },
                 "symbol": {
                     "type": "string",
@@ -1157,23 +3045,34 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number",
-                    "example": 0.5
+                    "type": "string",
+                    "example": "0.5"
                 },
                 "description": {
                     "type": "string",
                     "example": "Buying Bitcoin"
                 },
                 "price": {
-                    "type": "number",
-                    "example": 50000
+                    "type": "string",
+                    "example": "50000.00"
                 },
                 "status": {
                     "type": "string",
+                    "enum": [
+                        "pending",
+                        "completed",
+                        "failed",
+                        "cancelled"
+                    ],
                     "example": "completed"
                 },
                 "type": {
                     "type": "string",
+                    "enum": [
+                        "buy",
+                        "sell",
+                        "transfer"
+                    ],
                     "example": "buy"
                 }
             }
@@ -1197,6 +3096,17 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Doe"
                 },
+                "role": {
+                    "description": "Admin only",
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "operator",
+                        "trader",
+                        "read-only"
+                    ],
+                    "example": "trader"
+                },
                 "username": {
                     "type": "string",
                     "example": "johndoe"
@@ -1230,6 +3140,11 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Doe"
                 },
+                "role": {
+                    "description": "admin, operator, trader, read-only",
+                    "type": "string",
+                    "example": "trader"
+                },
                 "updated_at": {
                     "type": "string",
                     "example": "2023-01-01T00:00:00Z"
@@ -1239,6 +3154,82 @@ const docTemplate = `{
                     "example": "johndoe"
                 }
             }
+        },
+        "pricefeed.Metrics": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Failed quotes and database errors",
+                    "type": "integer",
+                    "example": 2
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "prices.example.com returned 503 Service Unavailable"
+                },
+                "last_poll_at": {
+                    "type": "string",
+                    "example": "2023-01-01T00:00:00Z"
+                },
+                "last_poll_duration": {
+                    "type": "string",
+                    "example": "1.2s"
+                },
+                "not_found": {
+                    "description": "Symbols the provider had no price for",
+                    "type": "integer",
+                    "example": 3
+                },
+                "polls": {
+                    "description": "Completed polling rounds",
+                    "type": "integer",
+                    "example": 42
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "http"
+                },
+                "quotes": {
+                    "description": "Quotes received",
+                    "type": "integer",
+                    "example": 420
+                },
+                "rejected": {
+                    "description": "Quotes that were invalid or older than the current price",
+                    "type": "integer",
+                    "example": 1
+                },
+                "stale_assets": {
+                    "description": "Assets marked stale after the last poll",
+                    "type": "integer",
+                    "example": 1
+                },
+                "updates": {
+                    "description": "Quotes that changed a price",
+                    "type": "integer",
+                    "example": 97
+                }
+            }
+        },
+        "pricefeed.Status": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "1m0s"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/pricefeed.Metrics"
+                },
+                "stale_after": {
+                    "type": "string",
+                    "example": "15m0s"
+                }
+            }
         }
     },
     "securityDefinitions": {
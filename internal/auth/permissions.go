@@ -0,0 +1,46 @@
+package auth
+
+import "go-api-test1/internal/models"
+
+// Permission names an action that can be granted to a role
+type Permission string
+
+const (
+	PermAssetsRead         Permission = "assets:read"
+	PermAssetsWrite        Permission = "assets:write"
+	PermUsersManage        Permission = "users:manage"
+	PermTransactionsRead   Permission = "transactions:read"
+	PermTransactionsWrite  Permission = "transactions:write"
+	PermTransactionsManage Permission = "transactions:manage"
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[string][]Permission{
+	models.RoleAdmin: {
+		PermAssetsRead, PermAssetsWrite,
+		PermUsersManage,
+		PermTransactionsRead, PermTransactionsWrite, PermTransactionsManage,
+	},
+	models.RoleOperator: {
+		PermAssetsRead,
+		PermTransactionsRead, PermTransactionsWrite, PermTransactionsManage,
+	},
+	models.RoleTrader: {
+		PermAssetsRead,
+		PermTransactionsRead, PermTransactionsWrite,
+	},
+	models.RoleReadOnly: {
+		PermAssetsRead,
+		PermTransactionsRead,
+	},
+}
+
+// HasPermission reports whether role grants perm. Unknown roles grant nothing.
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
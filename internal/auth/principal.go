@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"time"
+
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// principalKey is the gin context key the authenticated Principal is stored under
+const principalKey = "auth.principal"
+
+// Claims are the JWT claims carried by access tokens. The token ID is the
+// registered "jti" claim.
+type Claims struct {
+	UserID uint     `json:"user_id"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID    uint
+	Role      string
+	TokenID   string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// NewPrincipal builds a Principal from validated token claims
+func NewPrincipal(claims *Claims) *Principal {
+	p := &Principal{
+		UserID:  claims.UserID,
+		Role:    claims.Role,
+		TokenID: claims.ID,
+		Scopes:  claims.Scopes,
+	}
+	if claims.IssuedAt != nil {
+		p.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		p.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return p
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p *Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin
+}
+
+// HasScope reports whether the token was issued with the given scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal may perform perm. The role must grant the
+// permission and, if the token is scoped, the permission must be among its scopes.
+func (p *Principal) Can(perm Permission) bool {
+	if !HasPermission(p.Role, perm) {
+		return false
+	}
+	return len(p.Scopes) == 0 || p.HasScope(string(perm))
+}
+
+// SetPrincipal stores the authenticated principal in the request context
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// FromContext returns the authenticated principal for the request, if any
+func FromContext(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	p, ok := value.(*Principal)
+	return p, ok && p != nil
+}
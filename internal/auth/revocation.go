@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-api-test1/internal/models"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// DefaultRefreshInterval is how often the in-memory cache is reloaded from the
+// database so that revocations made by other instances are picked up
+const DefaultRefreshInterval = 30 * time.Second
+
+// RevocationStore keeps track of revoked access tokens. Revocations are persisted
+// in the revoked_tokens table and mirrored in memory so the auth middleware does
+// not need a database round trip on every request.
+type RevocationStore struct {
+	db              *gorm.DB
+	refreshInterval time.Duration
+	// reloads makes concurrent requests share a single reload
+	reloads singleflight.Group
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> token expiry
+	users    map[uint]time.Time   // user ID -> tokens issued at or before this time are revoked
+	loadedAt time.Time
+	// Revocations made by this instance since the current load began. The
+	// load may have read the table before they were committed, so they are
+	// merged into its snapshot.
+	recentTokens map[string]time.Time
+	recentUsers  map[uint]time.Time
+}
+
+// NewRevocationStore creates a RevocationStore backed by db
+func NewRevocationStore(db *gorm.DB) *RevocationStore {
+	return &RevocationStore{
+		db:              db,
+		refreshInterval: DefaultRefreshInterval,
+		tokens:          make(map[string]time.Time),
+		users:           make(map[uint]time.Time),
+		recentTokens:    make(map[string]time.Time),
+		recentUsers:     make(map[uint]time.Time),
+	}
+}
+
+// Load replaces the in-memory cache with the unexpired revocations from the
+// database and prunes expired rows. Concurrent calls share one reload.
+func (s *RevocationStore) Load() error {
+	_, err, _ := s.reloads.Do("load", func() (interface{}, error) {
+		return nil, s.load()
+	})
+	return err
+}
+
+// reloadIfStale reloads the cache if it is older than the refresh interval.
+// Callers that find it stale while another reload is running wait for that
+// one, and the staleness is checked again so that callers arriving just after
+// a reload do not start another.
+func (s *RevocationStore) reloadIfStale() error {
+	if !s.stale() {
+		return nil
+	}
+	_, err, _ := s.reloads.Do("load", func() (interface{}, error) {
+		if !s.stale() {
+			return nil, nil
+		}
+		return nil, s.load()
+	})
+	return err
+}
+
+// stale reports whether the cache is older than the refresh interval
+func (s *RevocationStore) stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.loadedAt) > s.refreshInterval
+}
+
+// load reads the revocations from the database. It must only run inside
+// s.reloads, so that loads do not overlap.
+func (s *RevocationStore) load() error {
+	now := time.Now()
+	s.mu.Lock()
+	s.recentTokens = make(map[string]time.Time)
+	s.recentUsers = make(map[uint]time.Time)
+	s.mu.Unlock()
+
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		slog.Error("Failed to prune expired revocations", "component", "revocation", "error", err)
+	}
+
+	var revoked []models.RevokedToken
+	if err := s.db.Where("expires_at >= ?", now).Find(&revoked).Error; err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revoked))
+	users := make(map[uint]time.Time)
+	for _, r := range revoked {
+		if r.JTI != "" {
+			tokens[r.JTI] = r.ExpiresAt
+			continue
+		}
+		if cutoff, ok := users[r.UserID]; !ok || r.CreatedAt.After(cutoff) {
+			users[r.UserID] = r.CreatedAt
+		}
+	}
+
+	s.mu.Lock()
+	for jti, expiresAt := range s.recentTokens {
+		tokens[jti] = expiresAt
+	}
+	for userID, cutoff := range s.recentUsers {
+		if cutoff.After(users[userID]) {
+			users[userID] = cutoff
+		}
+	}
+	s.tokens = tokens
+	s.users = users
+	s.loadedAt = now
+	s.mu.Unlock()
+
+	slog.Debug("Loaded revocations", "component", "revocation", "tokens", len(tokens), "users", len(users))
+	return nil
+}
+
+// RevokeToken revokes a single access token until it expires
+func (s *RevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	revoked := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&revoked).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = expiresAt
+	s.recentTokens[jti] = expiresAt
+	s.mu.Unlock()
+
+	slog.Info("Revoked token", "component", "revocation", "jti", jti, "user_id", userID)
+	return nil
+}
+
+// RevokeUser revokes every access token issued to the user so far, along with
+// all of the user's refresh tokens. maxTokenTTL bounds how long the revocation
+// has to be remembered.
+func (s *RevocationStore) RevokeUser(userID uint, maxTokenTTL time.Duration) error {
+	now := time.Now()
+	revoked := models.RevokedToken{
+		UserID:    userID,
+		ExpiresAt: now.Add(maxTokenTTL),
+		CreatedAt: now,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&revoked).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = now
+	s.recentUsers[userID] = now
+	s.mu.Unlock()
+
+	slog.Info("Revoked all tokens of user", "component", "revocation", "user_id", userID)
+	return nil
+}
+
+// IsRevoked reports whether the token identified by jti, issued to userID at
+// issuedAt, has been revoked
+func (s *RevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) bool {
+	if err := s.reloadIfStale(); err != nil {
+		slog.Error("Failed to reload revocations, using cached data", "component", "revocation", "error", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[jti]; ok {
+		return true
+	}
+	// JWT iat has second precision, so compare against the truncated cutoff
+	if cutoff, ok := s.users[userID]; ok && !issuedAt.After(cutoff.Truncate(time.Second)) {
+		return true
+	}
+	return false
+}
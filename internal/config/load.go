@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable that points at a config file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, a YAML config file, environment variables and command-line flags,
+// and validates it. The file is named by the -config flag or CONFIG_FILE.
+// Every setting has an environment variable and a flag named after it, e.g.
+// HTTP_READ_TIMEOUT and -http-read-timeout. Load returns the arguments left
+// after the flags.
+func Load(args []string) (*Config, []string, error) {
+	// Flags are parsed once to find the config file and again after the file
+	// and environment have been applied, so that they override both
+	path := os.Getenv(ConfigFileEnv)
+	probe := newFlagSet(Default(), &path)
+	probe.SetOutput(&bytes.Buffer{})
+	_ = probe.Parse(args)
+
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	fs := newFlagSet(cfg, &path)
+	if err := applyEnv(fs); err != nil {
+		return nil, nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile overlays the settings in a YAML file. Unknown keys are rejected so
+// that typos do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv sets every flag whose environment variable is set
+func applyEnv(fs *flag.FlagSet) error {
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		name := envName(f.Name)
+		if value := os.Getenv(name); value != "" {
+			if err := f.Value.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %w", name, value, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// envName returns the environment variable of a flag, e.g. DATABASE_URL for database-url
+func envName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+const usage = `usage: go-api-test1 [flags] [migrate <command> [n]]
+
+Settings are read from the defaults, the config file, the environment and the
+flags, with later sources taking precedence. Every flag can be set by the
+environment variable named after it, e.g. HTTP_READ_TIMEOUT for
+-http-read-timeout; the config file is also named by CONFIG_FILE.
+
+flags:
+`
+
+// newFlagSet creates a flag for every setting, bound to cfg. The config file
+// path is bound to path.
+func newFlagSet(cfg *Config, path *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("go-api-test1", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(path, "config", *path, "YAML config file")
+
+	fs.StringVar(&cfg.Environment, "environment", cfg.Environment, "environment; production refuses default secrets")
+	fs.TextVar(&cfg.RoundingMode, "rounding-mode", cfg.RoundingMode, "rounding of amounts and prices: half_even, half_up, down or up")
+	fs.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "how long responses to requests with an Idempotency-Key are kept")
+
+	fs.StringVar(&cfg.Server.Port, "port", cfg.Server.Port, "port to listen on")
+	fs.StringVar(&cfg.Server.MetricsAddr, "metrics-addr", cfg.Server.MetricsAddr, "address of the listener serving /metrics, empty to disable it")
+	fs.DurationVar(&cfg.Server.ReadTimeout, "http-read-timeout", cfg.Server.ReadTimeout, "time to read a whole request")
+	fs.DurationVar(&cfg.Server.ReadHeaderTimeout, "http-read-header-timeout", cfg.Server.ReadHeaderTimeout, "time to read request headers")
+	fs.DurationVar(&cfg.Server.WriteTimeout, "http-write-timeout", cfg.Server.WriteTimeout, "time to write a response")
+	fs.DurationVar(&cfg.Server.IdleTimeout, "http-idle-timeout", cfg.Server.IdleTimeout, "time keep-alive connections stay open between requests")
+	fs.IntVar(&cfg.Server.MaxHeaderBytes, "http-max-header-bytes", cfg.Server.MaxHeaderBytes, "maximum size of request headers")
+	fs.Int64Var(&cfg.Server.MaxBodyBytes, "http-max-body-bytes", cfg.Server.MaxBodyBytes, "maximum size of request bodies")
+	fs.DurationVar(&cfg.Server.ShutdownDelay, "shutdown-delay", cfg.Server.ShutdownDelay, "time readiness fails before the server stops accepting connections")
+	fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "time in-flight requests get to finish on shutdown")
+
+	fs.StringVar(&cfg.Database.URL, "database-url", cfg.Database.URL, "database connection string")
+	fs.IntVar(&cfg.Database.MaxOpenConns, "db-max-open-conns", cfg.Database.MaxOpenConns, "maximum open database connections, 0 for unlimited")
+	fs.IntVar(&cfg.Database.MaxIdleConns, "db-max-idle-conns", cfg.Database.MaxIdleConns, "maximum idle database connections")
+	fs.DurationVar(&cfg.Database.ConnMaxLifetime, "db-conn-max-lifetime", cfg.Database.ConnMaxLifetime, "time after which connections are replaced, 0 to keep them")
+	fs.DurationVar(&cfg.Database.ConnMaxIdleTime, "db-conn-max-idle-time", cfg.Database.ConnMaxIdleTime, "time after which idle connections are closed, 0 to keep them")
+	fs.StringVar(&cfg.Database.LogLevel, "db-log-level", cfg.Database.LogLevel, "query log level: silent, error, warn or info")
+	fs.DurationVar(&cfg.Database.SlowThreshold, "db-slow-threshold", cfg.Database.SlowThreshold, "duration above which queries are logged as slow, 0 to disable")
+	fs.IntVar(&cfg.Database.ConnectRetries, "db-connect-retries", cfg.Database.ConnectRetries, "connection retries at startup")
+	fs.DurationVar(&cfg.Database.ConnectBackoff, "db-connect-backoff", cfg.Database.ConnectBackoff, "wait before the first connection retry, doubled for each next one")
+
+	fs.StringVar(&cfg.Auth.JWTSecret, "jwt-secret", cfg.Auth.JWTSecret, "secret key signing access tokens")
+	fs.DurationVar(&cfg.Auth.AccessTokenTTL, "access-token-ttl", cfg.Auth.AccessTokenTTL, "access token lifetime")
+	fs.DurationVar(&cfg.Auth.RefreshTokenTTL, "refresh-token-ttl", cfg.Auth.RefreshTokenTTL, "refresh token lifetime")
+
+	fs.Var((*listValue)(&cfg.CORS.AllowedOrigins), "cors-allowed-origins", "comma-separated origins allowed to make cross-origin requests, * for any")
+
+	fs.TextVar(&cfg.Logging.Level, "log-level", cfg.Logging.Level, "minimum log level: debug, info, warn or error")
+	fs.StringVar(&cfg.Logging.Format, "log-format", cfg.Logging.Format, "log format: text or json")
+
+	fs.StringVar(&cfg.Tracing.Exporter, "tracing-exporter", cfg.Tracing.Exporter, "span exporter: otlp, stdout or none")
+	fs.Float64Var(&cfg.Tracing.SampleRatio, "tracing-sample-ratio", cfg.Tracing.SampleRatio, "share of new traces that are recorded, 0 to 1")
+	fs.StringVar(&cfg.Tracing.ServiceName, "otel-service-name", cfg.Tracing.ServiceName, "service name reported in traces")
+
+	fs.Float64Var(&cfg.RateLimit.RequestsPerSecond, "rate-limit-rps", cfg.RateLimit.RequestsPerSecond, "requests per second allowed per client, 0 to disable the limit")
+	fs.IntVar(&cfg.RateLimit.Burst, "rate-limit-burst", cfg.RateLimit.Burst, "requests a client may make at once")
+
+	fs.StringVar(&cfg.PriceFeed.Provider, "price-feed-provider", cfg.PriceFeed.Provider, "price feed provider: file, http or empty to disable")
+	fs.StringVar(&cfg.PriceFeed.File, "price-feed-file", cfg.PriceFeed.File, "price file of the file provider")
+	fs.StringVar(&cfg.PriceFeed.URL, "price-feed-url", cfg.PriceFeed.URL, "endpoint of the http provider, with a {symbol} placeholder")
+	fs.StringVar(&cfg.PriceFeed.PriceField, "price-feed-price-field", cfg.PriceFeed.PriceField, "JSON field holding the price")
+	fs.StringVar(&cfg.PriceFeed.TimeField, "price-feed-time-field", cfg.PriceFeed.TimeField, "JSON field holding the quote time")
+	fs.StringVar(&cfg.PriceFeed.APIKey, "price-feed-api-key", cfg.PriceFeed.APIKey, "sent as X-API-Key by the http provider")
+	fs.DurationVar(&cfg.PriceFeed.Timeout, "price-feed-timeout", cfg.PriceFeed.Timeout, "timeout of http provider requests")
+	fs.DurationVar(&cfg.PriceFeed.Interval, "price-feed-interval", cfg.PriceFeed.Interval, "time between polling rounds")
+	fs.DurationVar(&cfg.PriceFeed.StaleAfter, "price-stale-after", cfg.PriceFeed.StaleAfter, "age after which an asset's price is marked stale")
+
+	// Secrets are not printed as flag defaults
+	fs.Lookup("jwt-secret").DefValue = ""
+	fs.Lookup("price-feed-api-key").DefValue = ""
+	return fs
+}
+
+// listValue is a comma-separated flag value
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// minProductionSecretLength is the shortest JWT secret accepted in production
+const minProductionSecretLength = 32
+
+// Validate checks that every setting is usable and, in production, that no
+// default secrets are left in place. It reports all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT %q is not a valid port", c.Server.Port))
+	}
+	check(c.Server.ReadTimeout > 0, "HTTP_READ_TIMEOUT must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT must be positive")
+	check(c.Server.WriteTimeout > 0, "HTTP_WRITE_TIMEOUT must be positive")
+	check(c.Server.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "HTTP_MAX_HEADER_BYTES must be positive")
+	check(c.Server.MaxBodyBytes > 0, "HTTP_MAX_BODY_BYTES must be positive")
+	check(c.Server.ShutdownDelay >= 0, "SHUTDOWN_DELAY must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	if c.Server.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(c.Server.MetricsAddr); err != nil {
+			errs = append(errs, fmt.Errorf("METRICS_ADDR %q is not a host:port address", c.Server.MetricsAddr))
+		} else {
+			check(port != c.Server.Port, "METRICS_ADDR must not use the API's PORT")
+		}
+	}
+
+	check(c.Database.URL != "", "DATABASE_URL is required")
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative")
+	switch strings.ToLower(c.Database.LogLevel) {
+	case "silent", "error", "warn", "info":
+	default:
+		errs = append(errs, fmt.Errorf("DB_LOG_LEVEL %q is not silent, error, warn or info", c.Database.LogLevel))
+	}
+	check(c.Database.SlowThreshold >= 0, "DB_SLOW_THRESHOLD must not be negative")
+	check(c.Database.ConnectRetries >= 0, "DB_CONNECT_RETRIES must not be negative")
+	check(c.Database.ConnectBackoff > 0, "DB_CONNECT_BACKOFF must be positive")
+
+	check(c.Auth.JWTSecret != "", "JWT_SECRET is required")
+	check(c.Auth.AccessTokenTTL > 0, "ACCESS_TOKEN_TTL must be positive")
+	check(c.Auth.RefreshTokenTTL > 0, "REFRESH_TOKEN_TTL must be positive")
+	check(c.IdempotencyTTL > 0, "IDEMPOTENCY_TTL must be positive")
+
+	check(len(c.CORS.AllowedOrigins) > 0, "CORS_ALLOWED_ORIGINS must list at least one origin")
+
+	format := strings.ToLower(c.Logging.Format)
+	check(format == "text" || format == "json", "LOG_FORMAT %q is not text or json", c.Logging.Format)
+
+	switch c.Tracing.Exporter {
+	case "", "none", "otlp", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER %q is not otlp, stdout or none", c.Tracing.Exporter))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	check(c.RateLimit.RequestsPerSecond >= 0, "RATE_LIMIT_RPS must not be negative")
+	check(c.RateLimit.RequestsPerSecond == 0 || c.RateLimit.Burst > 0, "RATE_LIMIT_BURST must be positive")
+
+	switch c.PriceFeed.Provider {
+	case "", "file":
+	case "http":
+		check(c.PriceFeed.URL != "", "PRICE_FEED_URL is required by the http provider")
+	default:
+		errs = append(errs, fmt.Errorf("PRICE_FEED_PROVIDER %q is not file or http", c.PriceFeed.Provider))
+	}
+	check(c.PriceFeed.Timeout > 0, "PRICE_FEED_TIMEOUT must be positive")
+	check(c.PriceFeed.Interval > 0, "PRICE_FEED_INTERVAL must be positive")
+	check(c.PriceFeed.StaleAfter > 0, "PRICE_STALE_AFTER must be positive")
+
+	if c.IsProduction() {
+		check(c.Auth.JWTSecret != DefaultJWTSecret, "JWT_SECRET must be changed from its default in production")
+		check(len(c.Auth.JWTSecret) >= minProductionSecretLength, "JWT_SECRET must be at least %d characters in production", minProductionSecretLength)
+		check(c.Database.URL != DefaultDatabaseURL, "DATABASE_URL must be changed from its default in production")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -1,40 +1,61 @@
 package database
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/metrics"
+	"go-api-test1/internal/tracing"
 
 	"gorm.io/driver/postgres"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
-// Initialize initializes the database connection
-func Initialize(databaseURL string) (*gorm.DB, error) {
-	var db *gorm.DB
-	var err error
-
-	// Check if we're using SQLite (for development) or PostgreSQL (for production)
-	if databaseURL == "" || databaseURL == "sqlite://test.db" {
-		// Use SQLite for development/testing
-		db, err = gorm.Open(sqlite.Open("test.db"), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to connect to SQLite database: %w", err)
-		}
-		log.Println("Connected to SQLite database")
+// Drivers selected by ParseURL
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// sqliteBusyTimeout is how long SQLite waits for a lock held by another
+// connection before failing with "database is locked"
+const sqliteBusyTimeout = 5 * time.Second
+
+// maxConnectBackoff caps the wait between connection attempts
+const maxConnectBackoff = 30 * time.Second
+
+// Initialize connects to the database, retrying while it is unreachable, and
+// sizes its pool. Queries are logged to logger at the configured level.
+func Initialize(cfg config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	driver, dsn, err := ParseURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	level, err := ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialector gorm.Dialector
+	if driver == SQLite {
+		dialector = OpenSQLite(dsn)
 	} else {
-		// Use PostgreSQL for production
-		db, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
-		}
-		log.Println("Connected to PostgreSQL database")
+		dialector = postgres.Open(dsn)
+	}
+	db, err := connect(dialector, &gorm.Config{
+		Logger: newQueryLogger(logger, level, cfg.SlowThreshold),
+		// Timestamps are stored in UTC whatever the server's time zone
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	}, cfg.ConnectRetries, cfg.ConnectBackoff, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", driver, err)
 	}
+	logger.Info("Connected to database", "driver", driver)
 
 	// Configure connection pool
 	sqlDB, err := db.DB()
@@ -43,8 +64,127 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 	}
 
 	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if driver == SQLite && isMemory(dsn) {
+		// Every connection to :memory: is a separate database, so keep one
+		// connection open for the lifetime of the process
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+
+	// Trace queries run with a request's context
+	if err := db.Use(tracing.Plugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install tracing plugin: %w", err)
+	}
+
+	// Export query metrics; connection pool stats are registered by the
+	// caller with its metrics registry
+	if err := db.Use(metrics.Plugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install metrics plugin: %w", err)
+	}
 
 	return db, nil
 }
+
+// connect opens the database, retrying up to retries times with exponential
+// backoff while it cannot be reached, e.g. while it is still starting up
+func connect(dialector gorm.Dialector, gormConfig *gorm.Config, retries int, backoff time.Duration, logger *slog.Logger) (*gorm.DB, error) {
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialector, gormConfig)
+		if err == nil {
+			return db, nil
+		}
+		if db != nil {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+		if attempt > retries {
+			return nil, err
+		}
+		logger.Warn("Failed to connect to database, retrying", "attempt", attempt, "retry_in", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+// ParseURL returns the driver and data source name for a database URL:
+//
+//   - postgres://… and postgresql://… URLs and key=value DSNs use PostgreSQL
+//   - sqlite://path opens the SQLite file at path
+//   - file:… URIs and :memory: are passed to SQLite as they are
+//
+// SQLite DSNs get a busy timeout, foreign keys and, for files, the WAL
+// journal, unless they set those options themselves.
+func ParseURL(databaseURL string) (driver, dsn string, err error) {
+	switch {
+	case databaseURL == "":
+		return "", "", errors.New("no database URL configured")
+	case strings.HasPrefix(databaseURL, "postgres://"), strings.HasPrefix(databaseURL, "postgresql://"):
+		return Postgres, databaseURL, nil
+	case strings.HasPrefix(databaseURL, "sqlite://"):
+		dsn = strings.TrimPrefix(databaseURL, "sqlite://")
+		if dsn == "" || strings.HasPrefix(dsn, "?") {
+			return "", "", fmt.Errorf("database URL %q has no path", databaseURL)
+		}
+	case strings.HasPrefix(databaseURL, "file:"), strings.HasPrefix(databaseURL, ":memory:"):
+		dsn = databaseURL
+	case !strings.Contains(databaseURL, "://") && strings.Contains(databaseURL, "="):
+		return Postgres, databaseURL, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database URL %q, expected postgres://, sqlite://, file: or :memory:", redactURL(databaseURL))
+	}
+
+	options := []string{
+		fmt.Sprintf("_busy_timeout=%d", sqliteBusyTimeout.Milliseconds()),
+		"_foreign_keys=1",
+	}
+	if !isMemory(dsn) {
+		options = append(options, "_journal_mode=WAL")
+	}
+	for _, option := range options {
+		name, _, _ := strings.Cut(option, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + option
+		} else {
+			dsn += "?" + option
+		}
+	}
+	return SQLite, dsn, nil
+}
+
+// isMemory reports whether a SQLite DSN opens an in-memory database
+func isMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.HasPrefix(dsn, "file::memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// redactURL drops everything but the scheme of a URL that could not be
+// parsed, as it may contain credentials
+func redactURL(databaseURL string) string {
+	if scheme, _, ok := strings.Cut(databaseURL, "://"); ok {
+		return scheme + "://…"
+	}
+	return "…"
+}
+
+// ParseLogLevel parses the level of GORM's query log: silent, error, warn or info
+func ParseLogLevel(s string) (gormlogger.LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn", "":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	}
+	return 0, fmt.Errorf("unknown database log level %q (expected silent, error, warn or info)", s)
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqliteDriverName is the database/sql driver registered by this package
+const sqliteDriverName = "sqlite3_utc"
+
+func init() {
+	sql.Register(sqliteDriverName, utcDriver{})
+}
+
+// OpenSQLite returns the GORM dialector for a SQLite DSN. SQLite stores times
+// as text and compares them as text, so every time is written in UTC, like
+// PostgreSQL's timestamptz compares them; otherwise values written with the
+// local offset would not compare correctly against UTC bounds.
+func OpenSQLite(dsn string) gorm.Dialector {
+	return &sqlite.Dialector{DriverName: sqliteDriverName, DSN: dsn}
+}
+
+// utcDriver is the SQLite driver with connections that bind times in UTC
+type utcDriver struct{}
+
+func (utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// utcConn is a SQLite connection that converts time arguments to UTC
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue implements driver.NamedValueChecker. Other values are left
+// to the default conversion.
+func (utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	if t, ok := value.Value.(time.Time); ok {
+		value.Value = t.UTC()
+		return nil
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,132 @@
+// Package export writes transactions in file formats for other tools.
+//
+// Writers are streaming: each row is written as soon as it is passed to
+// Write, so an export of any size only holds one row in memory. Supported
+// formats are CSV, JSON Lines (one JSON object per line) and OFX 2.2
+// investment statements for personal finance and accounting software.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/models"
+)
+
+// Format describes an export format
+type Format struct {
+	ContentType string
+	Extension   string
+}
+
+// Formats are the supported export formats by name
+var Formats = map[string]Format{
+	"csv":   {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	"jsonl": {ContentType: "application/x-ndjson", Extension: "jsonl"},
+	"ofx":   {ContentType: "application/x-ofx", Extension: "ofx"},
+}
+
+// Writer writes the rows of an export
+type Writer interface {
+	Write(row models.TransactionExport) error
+	// Close writes any trailer and flushes buffered output; it does not close
+	// the underlying writer
+	Close() error
+}
+
+// Statement describes the export as a whole, for formats with a header
+type Statement struct {
+	AccountID string    // identifies the account the transactions belong to
+	From, To  time.Time // period covered
+}
+
+// NewWriter creates a Writer for format on w
+func NewWriter(format string, w io.Writer, statement Statement) (Writer, error) {
+	buffered := bufio.NewWriter(w)
+	switch format {
+	case "csv":
+		return newCSVWriter(buffered), nil
+	case "jsonl":
+		return &jsonlWriter{buf: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case "ofx":
+		return newOFXWriter(buffered, statement), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// csvHeader is the header row of CSV exports
+var csvHeader = []string{
+	"id", "created_at", "updated_at", "type", "direction", "status", "asset_id", "asset_symbol",
+	"amount", "price", "total_value", "user_id", "recipient_id", "description",
+}
+
+type csvWriter struct {
+	buf *bufio.Writer
+	csv *csv.Writer
+}
+
+func newCSVWriter(buf *bufio.Writer) *csvWriter {
+	w := &csvWriter{buf: buf, csv: csv.NewWriter(buf)}
+	w.csv.Write(csvHeader)
+	return w
+}
+
+func (w *csvWriter) Write(row models.TransactionExport) error {
+	recipient := ""
+	if row.RecipientID != nil {
+		recipient = strconv.FormatUint(uint64(*row.RecipientID), 10)
+	}
+	return w.csv.Write([]string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		row.UpdatedAt.UTC().Format(time.RFC3339),
+		row.Type,
+		row.Direction,
+		row.Status,
+		strconv.FormatUint(uint64(row.AssetID), 10),
+		CSVText(row.AssetSymbol),
+		row.Amount.String(),
+		row.Price.String(),
+		row.TotalValue.String(),
+		strconv.FormatUint(uint64(row.UserID), 10),
+		recipient,
+		CSVText(row.Description),
+	})
+}
+
+// CSVText escapes a free-text CSV cell. Spreadsheets run cells starting with
+// =, +, -, @, a tab or a carriage return as formulas, so those get a leading
+// apostrophe, which spreadsheets hide and treat as marking text.
+func CSVText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(row models.TransactionExport) error {
+	return w.encoder.Encode(row)
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.buf.Flush()
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/models"
+)
+
+// OFX statement constants. Asset prices carry no currency, so statements are
+// declared in USD.
+const (
+	ofxCurrency = "USD"
+	ofxBrokerID = "go-api-test1"
+	ofxTime     = "20060102150405.000"
+)
+
+// ofxWriter writes an OFX 2.2 investment statement. Buys and sells become
+// BUYOTHER and SELLOTHER transactions, transfers become TRANSFER in or out;
+// securities are identified by their ticker symbol.
+type ofxWriter struct {
+	buf *bufio.Writer
+	err error
+}
+
+func newOFXWriter(buf *bufio.Writer, statement Statement) *ofxWriter {
+	w := &ofxWriter{buf: buf}
+	now := time.Now()
+	w.printf(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<INVSTMTMSGSRSV1><INVSTMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<INVSTMTRS><DTASOF>%s</DTASOF><CURDEF>%s</CURDEF><INVACCTFROM><BROKERID>%s</BROKERID><ACCTID>%s</ACCTID></INVACCTFROM>
+<INVTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxDate(now), ofxDate(now), ofxCurrency, ofxBrokerID, escape(statement.AccountID),
+		ofxDate(statement.From), ofxDate(statement.To))
+	return w
+}
+
+func (w *ofxWriter) Write(row models.TransactionExport) error {
+	tran := fmt.Sprintf("<INVTRAN><FITID>%d</FITID><DTTRADE>%s</DTTRADE><MEMO>%s</MEMO></INVTRAN>",
+		row.ID, ofxDate(row.UpdatedAt), escape(row.Description))
+	secID := fmt.Sprintf("<SECID><UNIQUEID>%s</UNIQUEID><UNIQUEIDTYPE>TICKER</UNIQUEIDTYPE></SECID>", escape(row.AssetSymbol))
+
+	switch {
+	case row.Type == models.TransactionTypeBuy:
+		w.printf("<BUYOTHER><INVBUY>%s%s<UNITS>%s</UNITS><UNITPRICE>%s</UNITPRICE><TOTAL>%s</TOTAL><SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND></INVBUY></BUYOTHER>\n",
+			tran, secID, row.Amount, row.Price, row.TotalValue.Neg())
+	case row.Type == models.TransactionTypeSell:
+		w.printf("<SELLOTHER><INVSELL>%s%s<UNITS>%s</UNITS><UNITPRICE>%s</UNITPRICE><TOTAL>%s</TOTAL><SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND></INVSELL></SELLOTHER>\n",
+			tran, secID, row.Amount.Neg(), row.Price, row.TotalValue)
+	default:
+		action, units := "OUT", row.Amount.Neg()
+		if row.Direction == models.TransferDirectionIncoming {
+			action, units = "IN", row.Amount
+		}
+		w.printf("<TRANSFER>%s%s<SUBACCTSEC>CASH</SUBACCTSEC><UNITS>%s</UNITS><TFERACTION>%s</TFERACTION><POSTYPE>LONG</POSTYPE></TRANSFER>\n",
+			tran, secID, units, action)
+	}
+	return w.err
+}
+
+func (w *ofxWriter) Close() error {
+	w.printf("</INVTRANLIST>\n</INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1>\n</OFX>\n")
+	if w.err != nil {
+		return w.err
+	}
+	return w.buf.Flush()
+}
+
+// printf writes unless an earlier write failed
+func (w *ofxWriter) printf(format string, args ...interface{}) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.buf, format, args...)
+	}
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format(ofxTime) + "[0:GMT]"
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
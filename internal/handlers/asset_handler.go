@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"go-api-test1/internal/config"
 	"go-api-test1/internal/models"
+	"go-api-test1/internal/prices"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,40 +16,53 @@ import (
 
 // AssetHandler handles asset-related HTTP requests
 type AssetHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	logger *slog.Logger
 }
 
 // NewAssetHandler creates a new AssetHandler
-func NewAssetHandler(db *gorm.DB) *AssetHandler {
-	return &AssetHandler{db: db}
+func NewAssetHandler(db *gorm.DB, cfg *config.Config, logger *slog.Logger) *AssetHandler {
+	return &AssetHandler{db: db, cfg: cfg, logger: logger.With("component", "asset")}
 }
 
 // GetAssets retrieves all assets
 // @Summary      Get all assets
-// @Description  Get a list of all assets
+// @Description  Get a page of assets
 // @Tags         assets
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}  models.Asset
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Number of items to skip"
+// @Param        cursor  query     string  false  "Cursor from a previous page's pagination.next_cursor"
+// @Param        sort       query     string  false  "Comma-separated sort keys, prefix with - for descending: id, name, symbol, type, created_at"
+// @Param        type       query     string  false  "Filter by asset type"
+// @Param        symbol     query     string  false  "Filter by symbol"
+// @Param        is_active  query     bool    false  "Filter by active flag"
+// @Success      200  {object}  models.ListResponse{data=[]models.Asset}
+// @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /assets [get]
 func (h *AssetHandler) GetAssets(c *gin.Context) {
-	log.Printf("Asset: GetAssets request from %s", c.ClientIP())
+	h.logger.DebugContext(c, "GetAssets request")
 	
+	query, ok := parseListQuery(c, h.logger, h.db.WithContext(c.Request.Context()), &models.Asset{}, assetListSpec)
+	if !ok {
+		return
+	}
+
 	var assets []models.Asset
-	if err := h.db.Find(&assets).Error; err != nil {
-		log.Printf("Asset: Database error retrieving assets: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve assets",
-		})
+	page, err := query.Find(h.db.WithContext(c.Request.Context()), &assets)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving assets", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve assets")
 		return
 	}
 
-	log.Printf("Asset: Successfully retrieved %d assets", len(assets))
-	c.JSON(http.StatusOK, assets)
+	h.logger.InfoContext(c, "Retrieved assets", "count", len(assets), "total", page.Total)
+	c.JSON(http.StatusOK, models.ListResponse{Data: assets, Pagination: page})
 }
 
 // GetAsset retrieves a specific asset by ID
@@ -65,35 +81,26 @@ func (h *AssetHandler) GetAssets(c *gin.Context) {
 func (h *AssetHandler) GetAsset(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Asset: Invalid asset ID format: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Asset ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid asset ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Asset ID must be a valid number")
 		return
 	}
 
-	log.Printf("Asset: GetAsset request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "GetAsset request", "asset_id", id)
 
 	var asset models.Asset
-	if err := h.db.First(&asset, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&asset, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Asset: Asset not found with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Asset not found",
-				Message: "The requested asset does not exist",
-			})
+			h.logger.InfoContext(c, "Asset not found", "asset_id", id)
+			respondError(c, http.StatusNotFound, "Asset not found", "The requested asset does not exist")
 			return
 		}
-		log.Printf("Asset: Database error retrieving asset ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve asset",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving asset", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve asset")
 		return
 	}
 
-	log.Printf("Asset: Successfully retrieved asset ID: %d, name: %s", asset.ID, asset.Name)
+	h.logger.InfoContext(c, "Retrieved asset", "asset_id", asset.ID, "name", asset.Name)
 	c.JSON(http.StatusOK, asset)
 }
 
@@ -105,50 +112,57 @@ func (h *AssetHandler) GetAsset(c *gin.Context) {
 // @Produce      json
 // @Security     BearerAuth
 // @Param        asset body      models.CreateAssetRequest  true  "Asset data"
+// @Param        Idempotency-Key  header  string  false  "Makes retries of this request safe; the first response is replayed"
 // @Success      201  {object}  models.Asset
 // @Failure      400  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /assets [post]
 func (h *AssetHandler) CreateAsset(c *gin.Context) {
-	log.Printf("Asset: CreateAsset request from %s", c.ClientIP())
+	h.logger.DebugContext(c, "CreateAsset request")
 	
 	var createReq models.CreateAssetRequest
 	if err := c.ShouldBindJSON(&createReq); err != nil {
-		log.Printf("Asset: Invalid create request from %s: %v", c.ClientIP(), err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid create request", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("Asset: Creating asset with name: %s, symbol: %s, type: %s", createReq.Name, createReq.Symbol, createReq.Type)
+	h.logger.InfoContext(c, "Creating asset", "name", createReq.Name, "symbol", createReq.Symbol, "type", createReq.Type)
 
 	asset := models.Asset{
-		Name:        createReq.Name,
-		Symbol:      createReq.Symbol,
-		Type:        createReq.Type,
-		Description: createReq.Description,
-		Price:       createReq.Price,
-		IsActive:    true,
+		Name:          createReq.Name,
+		Symbol:        createReq.Symbol,
+		Type:          createReq.Type,
+		Description:   createReq.Description,
+		QuantityScale: createReq.QuantityScale,
+		PriceScale:    createReq.PriceScale,
+		IsActive:      true,
 	}
+	asset.Price = createReq.Price.Round(asset.PricingScale(), h.cfg.RoundingMode)
+	now := time.Now().UTC()
+	asset.PriceUpdatedAt = &now
 
-	if err := h.db.Create(&asset).Error; err != nil {
-		log.Printf("Asset: Database error creating asset: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to create asset",
-		})
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&asset).Error; err != nil {
+			return err
+		}
+		return prices.Record(tx, asset.ID, asset.Price, models.PriceSourceInitial, now)
+	})
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error creating asset", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to create asset")
 		return
 	}
 
-	log.Printf("Asset: Successfully created asset ID: %d, name: %s", asset.ID, asset.Name)
+	h.logger.InfoContext(c, "Created asset", "asset_id", asset.ID, "name", asset.Name)
 	c.JSON(http.StatusCreated, asset)
 }
 
 // UpdateAsset updates a specific asset
 // @Summary      Update asset
-// @Description  Update a specific asset by its ID
+// @Description  Update a specific asset by its ID. A price change is appended to the asset's price history.
 // @Tags         assets
 // @Accept       json
 // @Produce      json
@@ -163,46 +177,34 @@ func (h *AssetHandler) CreateAsset(c *gin.Context) {
 func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Asset: Invalid asset ID format for update: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Asset ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid asset ID format for update", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Asset ID must be a valid number")
 		return
 	}
 
-	log.Printf("Asset: UpdateAsset request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "UpdateAsset request", "asset_id", id)
 
 	var asset models.Asset
-	if err := h.db.First(&asset, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&asset, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Asset: Asset not found for update with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Asset not found",
-				Message: "The requested asset does not exist",
-			})
+			h.logger.InfoContext(c, "Asset not found for update", "asset_id", id)
+			respondError(c, http.StatusNotFound, "Asset not found", "The requested asset does not exist")
 			return
 		}
-		log.Printf("Asset: Database error retrieving asset for update ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve asset",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving asset for update", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve asset")
 		return
 	}
 
 	var updateReq models.UpdateAssetRequest
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		log.Printf("Asset: Invalid update request for asset ID: %d: %v", id, err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid update request", "asset_id", id, "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("Asset: Updating asset ID: %d with fields: name=%s, symbol=%s, type=%s, price=%.2f", 
-		id, updateReq.Name, updateReq.Symbol, updateReq.Type, updateReq.Price)
+	h.logger.InfoContext(c, "Updating asset", "asset_id", id,
+		"name", updateReq.Name, "symbol", updateReq.Symbol, "type", updateReq.Type, "price", updateReq.Price)
 
 	// Update fields if provided
 	if updateReq.Name != "" {
@@ -217,23 +219,40 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 	if updateReq.Description != "" {
 		asset.Description = updateReq.Description
 	}
-	if updateReq.Price > 0 {
-		asset.Price = updateReq.Price
+	if updateReq.QuantityScale != nil {
+		asset.QuantityScale = updateReq.QuantityScale
+	}
+	if updateReq.PriceScale != nil {
+		asset.PriceScale = updateReq.PriceScale
+	}
+	previousPrice := asset.Price
+	if updateReq.Price.IsPositive() {
+		// A manual price is current until the price feed reports a newer one
+		now := time.Now().UTC()
+		asset.Price = updateReq.Price.Round(asset.PricingScale(), h.cfg.RoundingMode)
+		asset.PriceUpdatedAt = &now
+		asset.PriceStale = false
 	}
 	if updateReq.IsActive != nil {
 		asset.IsActive = *updateReq.IsActive
 	}
 
-	if err := h.db.Save(&asset).Error; err != nil {
-		log.Printf("Asset: Database error updating asset ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to update asset",
-		})
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&asset).Error; err != nil {
+			return err
+		}
+		if asset.Price.Equal(previousPrice) {
+			return nil
+		}
+		return prices.Record(tx, asset.ID, asset.Price, models.PriceSourceManual, *asset.PriceUpdatedAt)
+	})
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error updating asset", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to update asset")
 		return
 	}
 
-	log.Printf("Asset: Successfully updated asset ID: %d, name: %s", asset.ID, asset.Name)
+	h.logger.InfoContext(c, "Updated asset", "asset_id", asset.ID, "name", asset.Name)
 	c.JSON(http.StatusOK, asset)
 }
 
@@ -253,45 +272,33 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 func (h *AssetHandler) DeleteAsset(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Asset: Invalid asset ID format for delete: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Asset ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid asset ID format for delete", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Asset ID must be a valid number")
 		return
 	}
 
-	log.Printf("Asset: DeleteAsset request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "DeleteAsset request", "asset_id", id)
 
 	var asset models.Asset
-	if err := h.db.First(&asset, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&asset, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Asset: Asset not found for delete with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Asset not found",
-				Message: "The requested asset does not exist",
-			})
+			h.logger.InfoContext(c, "Asset not found for delete", "asset_id", id)
+			respondError(c, http.StatusNotFound, "Asset not found", "The requested asset does not exist")
 			return
 		}
-		log.Printf("Asset: Database error retrieving asset for delete ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve asset",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving asset for delete", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve asset")
 		return
 	}
 
-	log.Printf("Asset: Deleting asset ID: %d, name: %s", asset.ID, asset.Name)
+	h.logger.InfoContext(c, "Deleting asset", "asset_id", asset.ID, "name", asset.Name)
 
-	if err := h.db.Delete(&asset).Error; err != nil {
-		log.Printf("Asset: Database error deleting asset ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to delete asset",
-		})
+	if err := h.db.WithContext(c.Request.Context()).Delete(&asset).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error deleting asset", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to delete asset")
 		return
 	}
 
-	log.Printf("Asset: Successfully deleted asset ID: %d, name: %s", asset.ID, asset.Name)
+	h.logger.InfoContext(c, "Deleted asset", "asset_id", asset.ID, "name", asset.Name)
 	c.JSON(http.StatusOK, gin.H{"message": "Asset deleted successfully"})
 }
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+	"go-api-test1/internal/prices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// maxImportRows bounds the number of assets a single import may contain
+const maxImportRows = 5000
+
+// maxImportBytes bounds the size of an import's body
+const maxImportBytes = 5 << 20
+
+// errTooManyImportRows is returned by the decoders once an import exceeds maxImportRows
+var errTooManyImportRows = fmt.Errorf("an import may contain at most %d assets", maxImportRows)
+
+// requiredImportColumns must be present in the header of a CSV import
+var requiredImportColumns = []string{"name", "symbol", "type", "price"}
+
+// importColumns are the columns a CSV import may have
+var importColumns = map[string]bool{
+	"name": true, "symbol": true, "type": true, "description": true,
+	"price": true, "quantity_scale": true, "price_scale": true,
+}
+
+// assetImportInput is one decoded row of an asset import
+type assetImportInput struct {
+	row     int
+	request models.CreateAssetRequest
+	errors  []string
+}
+
+// assetImportPlan is what an import does with one row
+type assetImportPlan struct {
+	result       models.AssetImportRow
+	asset        models.Asset
+	priceChanged bool
+}
+
+// ImportAssets creates or updates assets in bulk
+// @Summary      Import assets
+// @Description  Create assets in bulk from a CSV file (with a header row) or a JSON array of asset objects. Every row is validated like POST /assets and the response reports the outcome of each row. With upsert, rows whose symbol already exists update that asset instead of failing. With dry_run nothing is written. By default the import is atomic: if any row fails, no asset is written and the response is 422. With atomic=false valid rows are written and failed rows are reported.
+// @Tags         assets
+// @Accept       json
+// @Accept       text/csv
+// @Produce      json
+// @Security     BearerAuth
+// @Param        assets           body      []models.CreateAssetRequest  true   "Assets as a JSON array, or CSV with columns name, symbol, type, description, price, quantity_scale, price_scale"
+// @Param        dry_run          query     bool    false  "Validate and report without writing (default false)"
+// @Param        upsert           query     bool    false  "Update assets whose symbol already exists (default false)"
+// @Param        atomic           query     bool    false  "Write nothing if any row fails (default true)"
+// @Param        Idempotency-Key  header    string  false  "Makes retries of this request safe; the first response is replayed"
+// @Success      200  {object}  models.AssetImportReport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.AssetImportReport
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /assets/import [post]
+func (h *AssetHandler) ImportAssets(c *gin.Context) {
+	h.logger.DebugContext(c, "ImportAssets request")
+
+	report := models.AssetImportReport{Atomic: true}
+	for name, flag := range map[string]*bool{"dry_run": &report.DryRun, "upsert": &report.Upsert, "atomic": &report.Atomic} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid request", fmt.Sprintf("%s must be true or false", name))
+			return
+		}
+		*flag = value
+	}
+
+	var inputs []assetImportInput
+	var err error
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	if c.ContentType() == "text/csv" {
+		inputs, err = decodeCSVImport(body)
+	} else {
+		inputs, err = decodeJSONImport(body)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = fmt.Errorf("an import may be at most %d bytes", tooLarge.Limit)
+	}
+	if err == nil && len(inputs) == 0 {
+		err = errors.New("the import contains no assets")
+	}
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid import", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	symbols := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		if in.request.Symbol != "" {
+			symbols = append(symbols, in.request.Symbol)
+		}
+	}
+	// Soft-deleted assets still hold their symbol in the unique index
+	var found []models.Asset
+	if err := h.db.WithContext(c.Request.Context()).Unscoped().Where("symbol IN ?", symbols).Find(&found).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error looking up imported symbols", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to import assets")
+		return
+	}
+	existing := make(map[string]models.Asset, len(found))
+	for _, asset := range found {
+		existing[asset.Symbol] = asset
+	}
+
+	now := time.Now().UTC()
+	plans := planAssetImport(inputs, existing, report.Upsert, now, h.cfg.RoundingMode)
+	failed := false
+	for _, plan := range plans {
+		failed = failed || plan.result.Action == models.ImportActionError
+	}
+
+	switch {
+	case report.DryRun:
+	case report.Atomic && failed:
+	case report.Atomic:
+		err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			for i := range plans {
+				if err := applyAssetImport(tx, &plans[i], now); err != nil {
+					return fmt.Errorf("row %d: %w", plans[i].result.Row, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			h.logger.ErrorContext(c, "Database error importing assets", "error", err)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to import assets")
+			return
+		}
+		report.Applied = true
+	default:
+		for i := range plans {
+			plan := &plans[i]
+			if plan.result.Action == models.ImportActionError {
+				continue
+			}
+			err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+				return applyAssetImport(tx, plan, now)
+			})
+			if err != nil {
+				h.logger.ErrorContext(c, "Database error importing row", "row", plan.result.Row, "symbol", plan.result.Symbol, "error", err)
+				plan.result.Action = models.ImportActionError
+				plan.result.AssetID = nil
+				plan.result.Changes = nil
+				plan.result.Errors = []string{"failed to save asset"}
+			}
+		}
+		report.Applied = true
+	}
+
+	report.Rows = make([]models.AssetImportRow, len(plans))
+	for i, plan := range plans {
+		report.Rows[i] = plan.result
+		report.Summary.Total++
+		switch plan.result.Action {
+		case models.ImportActionCreate:
+			report.Summary.Created++
+		case models.ImportActionUpdate:
+			report.Summary.Updated++
+		case models.ImportActionUnchanged:
+			report.Summary.Unchanged++
+		case models.ImportActionError:
+			report.Summary.Failed++
+		}
+	}
+
+	h.logger.InfoContext(c, "Imported assets", "dry_run", report.DryRun, "applied", report.Applied,
+		"total", report.Summary.Total, "created", report.Summary.Created, "updated", report.Summary.Updated,
+		"unchanged", report.Summary.Unchanged, "failed", report.Summary.Failed)
+	status := http.StatusOK
+	if report.Atomic && !report.DryRun && failed {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, report)
+}
+
+// planAssetImport decides what to do with each row without writing anything;
+// prices are rounded with mode
+func planAssetImport(inputs []assetImportInput, existing map[string]models.Asset, upsert bool, now time.Time, mode money.RoundingMode) []assetImportPlan {
+	seen := make(map[string]int, len(inputs))
+	plans := make([]assetImportPlan, len(inputs))
+	for i, in := range inputs {
+		req := in.request
+		plan := &plans[i]
+		plan.result = models.AssetImportRow{Row: in.row, Symbol: req.Symbol, Errors: in.errors}
+
+		if req.Symbol != "" {
+			if first, ok := seen[req.Symbol]; ok {
+				plan.result.Errors = append(plan.result.Errors, fmt.Sprintf("duplicate symbol, first seen in row %d", first))
+			} else {
+				seen[req.Symbol] = in.row
+			}
+		}
+		current, exists := existing[req.Symbol]
+		if exists && current.DeletedAt.Valid {
+			plan.result.Errors = append(plan.result.Errors, "symbol belongs to a deleted asset")
+		} else if exists && !upsert {
+			plan.result.Errors = append(plan.result.Errors, "asset already exists")
+		}
+		if len(plan.result.Errors) > 0 {
+			plan.result.Action = models.ImportActionError
+			continue
+		}
+
+		if !exists {
+			plan.result.Action = models.ImportActionCreate
+			plan.asset = models.Asset{
+				Name:           req.Name,
+				Symbol:         req.Symbol,
+				Type:           req.Type,
+				Description:    req.Description,
+				QuantityScale:  req.QuantityScale,
+				PriceScale:     req.PriceScale,
+				PriceUpdatedAt: &now,
+				IsActive:       true,
+			}
+			plan.asset.Price = req.Price.Round(plan.asset.PricingScale(), mode)
+			continue
+		}
+
+		// Fields that are empty in the row keep their current value, as in PUT /assets/{id}
+		asset := current
+		var changes []string
+		if req.Name != asset.Name {
+			asset.Name = req.Name
+			changes = append(changes, "name")
+		}
+		if req.Type != asset.Type {
+			asset.Type = req.Type
+			changes = append(changes, "type")
+		}
+		if req.Description != "" && req.Description != asset.Description {
+			asset.Description = req.Description
+			changes = append(changes, "description")
+		}
+		if req.QuantityScale != nil && *req.QuantityScale != asset.AmountScale() {
+			asset.QuantityScale = req.QuantityScale
+			changes = append(changes, "quantity_scale")
+		}
+		if req.PriceScale != nil && *req.PriceScale != asset.PricingScale() {
+			asset.PriceScale = req.PriceScale
+			changes = append(changes, "price_scale")
+		}
+		if price := req.Price.Round(asset.PricingScale(), mode); !price.Equal(asset.Price) {
+			asset.Price = price
+			asset.PriceUpdatedAt = &now
+			asset.PriceStale = false
+			plan.priceChanged = true
+			changes = append(changes, "price")
+		}
+
+		id := asset.ID
+		plan.asset = asset
+		plan.result.AssetID = &id
+		plan.result.Changes = changes
+		plan.result.Action = models.ImportActionUpdate
+		if len(changes) == 0 {
+			plan.result.Action = models.ImportActionUnchanged
+		}
+	}
+	return plans
+}
+
+// applyAssetImport writes a planned row and records any price change
+func applyAssetImport(tx *gorm.DB, plan *assetImportPlan, now time.Time) error {
+	switch plan.result.Action {
+	case models.ImportActionCreate:
+		if err := tx.Create(&plan.asset).Error; err != nil {
+			return err
+		}
+		id := plan.asset.ID
+		plan.result.AssetID = &id
+		return prices.Record(tx, plan.asset.ID, plan.asset.Price, models.PriceSourceInitial, now)
+	case models.ImportActionUpdate:
+		if err := tx.Save(&plan.asset).Error; err != nil {
+			return err
+		}
+		if !plan.priceChanged {
+			return nil
+		}
+		return prices.Record(tx, plan.asset.ID, plan.asset.Price, models.PriceSourceImport, now)
+	}
+	return nil
+}
+
+// decodeJSONImport reads a JSON array of assets, one element at a time so that
+// it can stop after maxImportRows. Rows that do not decode are reported as row
+// errors rather than failing the import.
+func decodeJSONImport(body io.Reader) ([]assetImportInput, error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, jsonImportError(err)
+	}
+	var inputs []assetImportInput
+	for decoder.More() {
+		if len(inputs) == maxImportRows {
+			return nil, errTooManyImportRows
+		}
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, jsonImportError(err)
+		}
+		in := assetImportInput{row: len(inputs) + 1}
+		if err := json.Unmarshal(item, &in.request); err != nil {
+			in.errors = []string{err.Error()}
+		} else {
+			in.errors = validateImportRow(&in.request)
+		}
+		inputs = append(inputs, in)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, jsonImportError(err)
+	}
+	return inputs, nil
+}
+
+// jsonImportError explains why a body is not a JSON array of assets, keeping
+// errors from reading the body, such as it being too large, unwrappable
+func jsonImportError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return errors.New("body must be a JSON array of assets")
+	}
+	return fmt.Errorf("body must be a JSON array of assets: %w", err)
+}
+
+// decodeCSVImport reads assets from CSV with a header row naming the columns
+func decodeCSVImport(body io.Reader) ([]assetImportInput, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !importColumns[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	var inputs []assetImportInput
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return inputs, nil
+		}
+		if len(inputs) == maxImportRows {
+			return nil, errTooManyImportRows
+		}
+		in := assetImportInput{row: row}
+		if err != nil {
+			if !errors.Is(err, csv.ErrFieldCount) {
+				return nil, fmt.Errorf("invalid CSV: %w", err)
+			}
+			in.errors = []string{fmt.Sprintf("expected %d fields, got %d", len(header), len(record))}
+			inputs = append(inputs, in)
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		in.request = models.CreateAssetRequest{
+			Name:        field("name"),
+			Symbol:      field("symbol"),
+			Type:        field("type"),
+			Description: field("description"),
+		}
+		if raw := field("price"); raw != "" {
+			if in.request.Price, err = money.NewFromString(raw); err != nil {
+				in.errors = append(in.errors, "price: "+err.Error())
+			}
+		}
+		for _, name := range []string{"quantity_scale", "price_scale"} {
+			raw := field(name)
+			if raw == "" {
+				continue
+			}
+			value, err := strconv.ParseInt(raw, 10, 32)
+			if err != nil {
+				in.errors = append(in.errors, fmt.Sprintf("%s: invalid integer %q", name, raw))
+				continue
+			}
+			scale := int32(value)
+			if name == "quantity_scale" {
+				in.request.QuantityScale = &scale
+			} else {
+				in.request.PriceScale = &scale
+			}
+		}
+		if len(in.errors) == 0 {
+			in.errors = validateImportRow(&in.request)
+		}
+		inputs = append(inputs, in)
+	}
+}
+
+// validateImportRow checks a row against the binding rules of CreateAssetRequest
+func validateImportRow(req *models.CreateAssetRequest) []string {
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []string{err.Error()}
+	}
+	messages := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		name := fe.Field()
+		if field, ok := reflect.TypeOf(*req).FieldByName(fe.StructField()); ok {
+			name = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		switch fe.Tag() {
+		case "required":
+			messages[i] = name + " is required"
+		case "min":
+			messages[i] = fmt.Sprintf("%s must be at least %s", name, fe.Param())
+		case "max":
+			messages[i] = fmt.Sprintf("%s must be at most %s", name, fe.Param())
+		default:
+			messages[i] = fmt.Sprintf("%s failed the %s rule", name, fe.Tag())
+		}
+	}
+	return messages
+}
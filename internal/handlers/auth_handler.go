@@ -1,11 +1,19 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"go-api-test1/internal/auth"
 	"go-api-test1/internal/config"
+	"go-api-test1/internal/metrics"
 	"go-api-test1/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -14,14 +22,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// errRefreshTokenReused is returned when a refresh token has already been rotated or revoked
+var errRefreshTokenReused = errors.New("refresh token reuse detected")
+
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	revocations *auth.RevocationStore
+	cfg         *config.Config
+	logger      *slog.Logger
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, revocations *auth.RevocationStore, cfg *config.Config, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{db: db, revocations: revocations, cfg: cfg, logger: logger.With("component", "auth")}
 }
 
 // Register registers a new user
@@ -31,46 +45,39 @@ func NewAuthHandler(db *gorm.DB) *AuthHandler {
 // @Accept       json
 // @Produce      json
 // @Param        user body      models.RegisterRequest  true  "User registration data"
+// @Param        Idempotency-Key  header  string  false  "Makes retries of this request safe; the first response is replayed"
 // @Success      201  {object}  models.AuthResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
-	log.Printf("Auth: Registration attempt from %s", c.ClientIP())
+	h.logger.DebugContext(c, "Registration attempt")
 	
 	var registerReq models.RegisterRequest
 	if err := c.ShouldBindJSON(&registerReq); err != nil {
-		log.Printf("Auth: Invalid registration request from %s: %v", c.ClientIP(), err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid registration request", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("Auth: Processing registration for email: %s, username: %s", registerReq.Email, registerReq.Username)
+	h.logger.InfoContext(c, "Processing registration", "email", registerReq.Email, "username", registerReq.Username)
 
 	// Check if user already exists
 	var existingUser models.User
-	if err := h.db.Where("email = ? OR username = ?", registerReq.Email, registerReq.Username).First(&existingUser).Error; err == nil {
-		log.Printf("Auth: Registration failed - user already exists with email: %s or username: %s", registerReq.Email, registerReq.Username)
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Error:   "User already exists",
-			Message: "A user with this email or username already exists",
-		})
+	if err := h.db.WithContext(c.Request.Context()).Where("email = ? OR username = ?", registerReq.Email, registerReq.Username).First(&existingUser).Error; err == nil {
+		h.logger.InfoContext(c, "Registration failed, user already exists", "email", registerReq.Email, "username", registerReq.Username)
+		respondError(c, http.StatusConflict, "User already exists", "A user with this email or username already exists")
 		return
 	}
 
 	// Hash password
-	log.Printf("Auth: Hashing password for user: %s", registerReq.Email)
+	h.logger.DebugContext(c, "Hashing password", "email", registerReq.Email)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerReq.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Auth: Password hashing failed for user: %s: %v", registerReq.Email, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Password hashing error",
-			Message: "Failed to hash password",
-		})
+		h.logger.ErrorContext(c, "Password hashing failed", "email", registerReq.Email, "error", err)
+		respondError(c, http.StatusInternalServerError, "Password hashing error", "Failed to hash password")
 		return
 	}
 
@@ -81,37 +88,41 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Password:  string(hashedPassword),
 		FirstName: registerReq.FirstName,
 		LastName:  registerReq.LastName,
+		Role:      models.RoleTrader,
 		IsActive:  true,
 	}
 
-	log.Printf("Auth: Creating user in database: %s", registerReq.Email)
-	if err := h.db.Create(&user).Error; err != nil {
-		log.Printf("Auth: Database error creating user: %s: %v", registerReq.Email, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to create user",
-		})
+	h.logger.DebugContext(c, "Creating user in database", "email", registerReq.Email)
+	if err := h.db.WithContext(c.Request.Context()).Create(&user).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error creating user", "email", registerReq.Email, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to create user")
 		return
 	}
 
-	log.Printf("Auth: User created successfully with ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Created user", "user_id", user.ID, "email", user.Email)
 
 	// Generate JWT token
-	log.Printf("Auth: Generating JWT token for user ID: %d", user.ID)
-	token, err := h.generateToken(user.ID)
+	h.logger.DebugContext(c, "Generating JWT token", "user_id", user.ID)
+	token, err := h.generateToken(c, user)
 	if err != nil {
-		log.Printf("Auth: Token generation failed for user ID: %d: %v", user.ID, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Token generation error",
-			Message: "Failed to generate authentication token",
-		})
+		h.logger.ErrorContext(c, "Token generation failed", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Token generation error", "Failed to generate authentication token")
 		return
 	}
 
-	log.Printf("Auth: Registration successful for user ID: %d, email: %s", user.ID, user.Email)
+	refreshToken, _, err := h.createRefreshToken(c, h.db.WithContext(c.Request.Context()), user.ID, "", "")
+	if err != nil {
+		h.logger.ErrorContext(c, "Refresh token generation failed", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Token generation error", "Failed to generate refresh token")
+		return
+	}
+
+	h.logger.InfoContext(c, "Registration successful", "user_id", user.ID, "email", user.Email)
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(h.cfg.Auth.AccessTokenTTL.Seconds()),
+		User:         user,
 	})
 }
 
@@ -128,101 +139,323 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
-	log.Printf("Auth: Login attempt from %s", c.ClientIP())
+	h.logger.DebugContext(c, "Login attempt")
 	
 	var loginReq models.LoginRequest
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		log.Printf("Auth: Invalid login request from %s: %v", c.ClientIP(), err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid login request", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("Auth: Processing login for email: %s", loginReq.Email)
+	h.logger.InfoContext(c, "Processing login", "email", loginReq.Email)
 
 	// Find user by email
 	var user models.User
-	if err := h.db.Where("email = ?", loginReq.Email).First(&user).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).Where("email = ?", loginReq.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Auth: Login failed - user not found with email: %s", loginReq.Email)
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-				Error:   "Invalid credentials",
-				Message: "Email or password is incorrect",
-			})
+			h.logger.InfoContext(c, "Login failed, user not found", "email", loginReq.Email)
+			metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+			respondError(c, http.StatusUnauthorized, "Invalid credentials", "Email or password is incorrect")
 			return
 		}
-		log.Printf("Auth: Database error retrieving user: %s: %v", loginReq.Email, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve user",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving user", "email", loginReq.Email, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve user")
 		return
 	}
 
-	log.Printf("Auth: User found with ID: %d, email: %s", user.ID, user.Email)
+	h.logger.DebugContext(c, "User found", "user_id", user.ID, "email", user.Email)
 
 	// Check if user is active
 	if !user.IsActive {
-		log.Printf("Auth: Login failed - account disabled for user ID: %d, email: %s", user.ID, user.Email)
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error:   "Account disabled",
-			Message: "Your account has been disabled",
-		})
+		h.logger.InfoContext(c, "Login failed, account disabled", "user_id", user.ID, "email", user.Email)
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		respondError(c, http.StatusUnauthorized, "Account disabled", "Your account has been disabled")
 		return
 	}
 
 	// Verify password
-	log.Printf("Auth: Verifying password for user ID: %d", user.ID)
+	h.logger.DebugContext(c, "Verifying password", "user_id", user.ID)
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginReq.Password)); err != nil {
-		log.Printf("Auth: Login failed - invalid password for user ID: %d, email: %s", user.ID, user.Email)
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error:   "Invalid credentials",
-			Message: "Email or password is incorrect",
-		})
+		h.logger.WarnContext(c, "Login failed, invalid password", "user_id", user.ID, "email", user.Email)
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		respondError(c, http.StatusUnauthorized, "Invalid credentials", "Email or password is incorrect")
 		return
 	}
 
-	log.Printf("Auth: Password verified successfully for user ID: %d", user.ID)
+	h.logger.DebugContext(c, "Password verified", "user_id", user.ID)
 
 	// Generate JWT token
-	log.Printf("Auth: Generating JWT token for user ID: %d", user.ID)
-	token, err := h.generateToken(user.ID)
+	h.logger.DebugContext(c, "Generating JWT token", "user_id", user.ID)
+	token, err := h.generateToken(c, user)
+	if err != nil {
+		h.logger.ErrorContext(c, "Token generation failed", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Token generation error", "Failed to generate authentication token")
+		return
+	}
+
+	refreshToken, _, err := h.createRefreshToken(c, h.db.WithContext(c.Request.Context()), user.ID, "", loginReq.DeviceName)
 	if err != nil {
-		log.Printf("Auth: Token generation failed for user ID: %d: %v", user.ID, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Token generation error",
-			Message: "Failed to generate authentication token",
-		})
+		h.logger.ErrorContext(c, "Refresh token generation failed", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Token generation error", "Failed to generate refresh token")
 		return
 	}
 
-	log.Printf("Auth: Login successful for user ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Login successful", "user_id", user.ID, "email", user.Email)
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
 	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(h.cfg.Auth.AccessTokenTTL.Seconds()),
+		User:         user,
 	})
 }
 
-// generateToken generates a JWT token for the user
-func (h *AuthHandler) generateToken(userID uint) (string, error) {
-	log.Printf("Auth: Creating JWT token for user ID: %d", userID)
-	
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-		"iat":     time.Now().Unix(),
+// Refresh exchanges a refresh token for a new access token and refresh token
+// @Summary      Refresh access token
+// @Description  Rotate a refresh token and return a new access token. Replaying a refresh token that was already used revokes every token issued from the same login.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token body      models.RefreshRequest  true  "Refresh token"
+// @Success      200  {object}  models.AuthResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	h.logger.DebugContext(c, "Token refresh attempt")
+
+	var refreshReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		h.logger.InfoContext(c, "Invalid refresh request", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := h.db.WithContext(c.Request.Context()).Where("token_hash = ?", hashToken(refreshReq.RefreshToken)).First(&stored).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			h.logger.InfoContext(c, "Refresh failed, unknown refresh token")
+			respondError(c, http.StatusUnauthorized, "Invalid refresh token", "The refresh token is invalid or has expired")
+			return
+		}
+		h.logger.ErrorContext(c, "Database error retrieving refresh token", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve refresh token")
+		return
+	}
+
+	if stored.RotatedAt != nil {
+		h.logger.WarnContext(c, "Refresh token reuse detected, revoking family", "user_id", stored.UserID, "family_id", stored.FamilyID)
+		h.revokeTokenFamily(c.Request.Context(), stored.FamilyID)
+		respondError(c, http.StatusUnauthorized, "Invalid refresh token", "The refresh token has already been used; please log in again")
+		return
+	}
+
+	if stored.RevokedAt != nil {
+		h.logger.WarnContext(c, "Refresh failed, revoked token presented", "user_id", stored.UserID, "family_id", stored.FamilyID)
+		respondError(c, http.StatusUnauthorized, "Invalid refresh token", "The refresh token has been revoked; please log in again")
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		h.logger.InfoContext(c, "Refresh failed, token expired", "user_id", stored.UserID)
+		respondError(c, http.StatusUnauthorized, "Invalid refresh token", "The refresh token is invalid or has expired")
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(c.Request.Context()).First(&user, stored.UserID).Error; err != nil || !user.IsActive {
+		h.logger.InfoContext(c, "Refresh failed, user not found or disabled", "user_id", stored.UserID)
+		h.revokeTokenFamily(c.Request.Context(), stored.FamilyID)
+		respondError(c, http.StatusUnauthorized, "Account disabled", "Your account has been disabled")
+		return
+	}
+
+	// Rotate: issue the successor and mark the presented token as used in one
+	// transaction. The conditional update guards against two concurrent
+	// refreshes with the same token both succeeding.
+	var refreshToken string
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		raw, next, err := h.createRefreshToken(c, tx, user.ID, stored.FamilyID, stored.DeviceName)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", stored.ID).
+			Updates(map[string]interface{}{"rotated_at": time.Now(), "replaced_by_id": next.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+		refreshToken = raw
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			h.logger.WarnContext(c, "Concurrent refresh token reuse detected, revoking family", "user_id", user.ID, "family_id", stored.FamilyID)
+			h.revokeTokenFamily(c.Request.Context(), stored.FamilyID)
+			respondError(c, http.StatusUnauthorized, "Invalid refresh token", "The refresh token has already been used; please log in again")
+			return
+		}
+		h.logger.ErrorContext(c, "Database error rotating refresh token", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to rotate refresh token")
+		return
+	}
+
+	token, err := h.generateToken(c, user)
+	if err != nil {
+		h.logger.ErrorContext(c, "Token generation failed", "user_id", user.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Token generation error", "Failed to generate authentication token")
+		return
+	}
+
+	h.logger.InfoContext(c, "Token refresh successful", "user_id", user.ID)
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(h.cfg.Auth.AccessTokenTTL.Seconds()),
+		User:         user,
+	})
+}
+
+// Logout revokes the caller's access token and, if given, its refresh token family
+// @Summary      Logout user
+// @Description  Revoke the current access token. If a refresh token is supplied, every refresh token issued from the same login is revoked too.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        token body      models.LogoutRequest  false  "Refresh token to revoke"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	principal, ok := auth.FromContext(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	h.logger.DebugContext(c, "Logout request", "user_id", principal.UserID)
+
+	var logoutReq models.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&logoutReq); err != nil {
+			h.logger.InfoContext(c, "Invalid logout request", "error", err)
+			respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	if err := h.revocations.RevokeToken(principal.TokenID, principal.UserID, principal.ExpiresAt); err != nil {
+		h.logger.ErrorContext(c, "Failed to revoke token", "user_id", principal.UserID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to revoke token")
+		return
+	}
+
+	if logoutReq.RefreshToken != "" {
+		var stored models.RefreshToken
+		err := h.db.WithContext(c.Request.Context()).Where("token_hash = ? AND user_id = ?", hashToken(logoutReq.RefreshToken), principal.UserID).First(&stored).Error
+		if err == nil {
+			h.revokeTokenFamily(c.Request.Context(), stored.FamilyID)
+		} else if err != gorm.ErrRecordNotFound {
+			h.logger.ErrorContext(c, "Database error retrieving refresh token on logout", "error", err)
+		}
+	}
+
+	h.logger.InfoContext(c, "Logout successful", "user_id", principal.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// generateToken generates a short-lived JWT access token for the user
+func (h *AuthHandler) generateToken(ctx context.Context, user models.User) (string, error) {
+	userID := user.ID
+	h.logger.DebugContext(ctx, "Creating JWT token", "user_id", userID)
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := auth.Claims{
+		UserID: userID,
+		Role:   user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.cfg.Auth.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.Load().JWTSecret))
+	tokenString, err := token.SignedString([]byte(h.cfg.Auth.JWTSecret))
 	
 	if err != nil {
-		log.Printf("Auth: Failed to sign JWT token for user ID: %d: %v", userID, err)
+		h.logger.ErrorContext(ctx, "Failed to sign JWT token", "user_id", userID, "error", err)
 		return "", err
 	}
 	
-	log.Printf("Auth: JWT token created successfully for user ID: %d", userID)
+	h.logger.DebugContext(ctx, "Created JWT token", "user_id", userID)
 	return tokenString, nil
 }
+
+// createRefreshToken generates a random refresh token and stores its hash.
+// An empty familyID starts a new family, i.e. a new login on a device.
+func (h *AuthHandler) createRefreshToken(ctx context.Context, tx *gorm.DB, userID uint, familyID, deviceName string) (string, *models.RefreshToken, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return "", nil, err
+		}
+	}
+
+	refreshToken := &models.RefreshToken{
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  hashToken(raw),
+		DeviceName: deviceName,
+		ExpiresAt:  time.Now().Add(h.cfg.Auth.RefreshTokenTTL),
+	}
+	if err := tx.Create(refreshToken).Error; err != nil {
+		return "", nil, err
+	}
+
+	h.logger.DebugContext(ctx, "Created refresh token", "user_id", userID, "family_id", familyID)
+	return raw, refreshToken, nil
+}
+
+// revokeTokenFamily revokes every refresh token issued from the same login
+func (h *AuthHandler) revokeTokenFamily(ctx context.Context, familyID string) {
+	result := h.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		h.logger.ErrorContext(ctx, "Failed to revoke refresh token family", "family_id", familyID, "error", result.Error)
+		return
+	}
+	h.logger.InfoContext(ctx, "Revoked refresh token family", "family_id", familyID, "count", result.RowsAffected)
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 of a refresh token; only the hash is persisted
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeDeadline keeps downloads that take long to produce, such as exports and
+// reports, from being cut off by the server's WriteTimeout. The timeout bounds
+// a whole response, so instead each call to extend moves the connection's write
+// deadline to timeout from now: it then limits how long a download may stall,
+// not how long it may take.
+type writeDeadline struct {
+	controller *http.ResponseController
+	timeout    time.Duration
+	movedAt    time.Time
+}
+
+// newWriteDeadline moves the write deadline of c's connection to timeout from now
+func newWriteDeadline(c *gin.Context, timeout time.Duration) *writeDeadline {
+	d := &writeDeadline{controller: http.NewResponseController(c.Writer), timeout: timeout}
+	d.extend()
+	return d
+}
+
+// extend moves the write deadline to timeout from now, at most once a second.
+// Writers without deadlines, such as test recorders, are left as they are.
+func (d *writeDeadline) extend() {
+	now := time.Now()
+	if now.Sub(d.movedAt) < time.Second {
+		return
+	}
+	d.movedAt = now
+	d.controller.SetWriteDeadline(now.Add(d.timeout))
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go-api-test1/internal/migrate"
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/pricefeed"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Readiness check timeouts
+const (
+	databaseCheckTimeout   = time.Second
+	migrationsCheckTimeout = 2 * time.Second
+	priceFeedCheckTimeout  = time.Second
+)
+
+var (
+	// errShuttingDown fails readiness once shutdown has begun
+	errShuttingDown = errors.New("shutting down")
+	// errCheckSkipped marks a check that does not apply to this instance
+	errCheckSkipped = errors.New("skipped")
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	db       *gorm.DB
+	migrator *migrate.Migrator
+	feed     *pricefeed.Worker
+	logger   *slog.Logger
+	draining atomic.Bool
+}
+
+// NewHealthHandler creates a new HealthHandler; feed is nil when the price feed is disabled
+func NewHealthHandler(db *gorm.DB, migrator *migrate.Migrator, feed *pricefeed.Worker, logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{db: db, migrator: migrator, feed: feed, logger: logger.With("component", "health")}
+}
+
+// Drain makes the readiness probe fail from now on, so that load balancers
+// stop routing new requests here while in-flight ones finish
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and serving requests. It checks no
+// dependencies, so an outage of the database does not get the process
+// restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{Status: models.HealthOK})
+}
+
+// Ready reports whether this instance can serve traffic: the database answers
+// a ping, every known migration is applied and, if enabled, the price feed
+// polled recently. Each check runs with its own timeout. It returns 503 with
+// the failed checks, and always fails once Drain was called.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	response := models.HealthResponse{Status: models.HealthOK, Checks: map[string]models.HealthCheck{}}
+	checks := []struct {
+		name    string
+		timeout time.Duration
+		run     func(ctx context.Context) (string, error)
+	}{
+		{"shutdown", 0, h.checkShutdown},
+		{"database", databaseCheckTimeout, h.checkDatabase},
+		{"migrations", migrationsCheckTimeout, h.checkMigrations},
+		{"price_feed", priceFeedCheckTimeout, h.checkPriceFeed},
+	}
+	for _, check := range checks {
+		result := runHealthCheck(c.Request.Context(), check.timeout, check.run)
+		if result.Status == models.HealthFail {
+			response.Status = models.HealthFail
+			h.logger.WarnContext(c, "Readiness check failed", "check", check.name, "error", result.Error)
+		}
+		response.Checks[check.name] = result
+	}
+
+	status := http.StatusOK
+	if response.Status != models.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// runHealthCheck runs one check, bounded by timeout unless it is 0
+func runHealthCheck(ctx context.Context, timeout time.Duration, run func(ctx context.Context) (string, error)) models.HealthCheck {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	detail, err := run(ctx)
+	result := models.HealthCheck{Status: models.HealthOK, Duration: time.Since(start).String(), Detail: detail}
+	switch {
+	case errors.Is(err, errCheckSkipped):
+		result.Status = models.HealthSkip
+	case err != nil:
+		result.Status = models.HealthFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (h *HealthHandler) checkShutdown(ctx context.Context) (string, error) {
+	if h.draining.Load() {
+		return "", errShuttingDown
+	}
+	return "", nil
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) (string, error) {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return "", err
+	}
+	return "", sqlDB.PingContext(ctx)
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) (string, error) {
+	statuses, err := h.migrator.Status(ctx)
+	if err != nil {
+		return "", err
+	}
+	var current, latest int64
+	pending := 0
+	for _, status := range statuses {
+		if status.Missing {
+			continue
+		}
+		latest = status.Version
+		if !status.Applied {
+			pending++
+			continue
+		}
+		if status.Modified {
+			return "", fmt.Errorf("migration %d was modified after it was applied", status.Version)
+		}
+		current = status.Version
+	}
+	detail := fmt.Sprintf("version %d of %d", current, latest)
+	if pending > 0 {
+		return detail, fmt.Errorf("%d migrations pending", pending)
+	}
+	return detail, nil
+}
+
+func (h *HealthHandler) checkPriceFeed(ctx context.Context) (string, error) {
+	if h.feed == nil {
+		return "disabled", errCheckSkipped
+	}
+	return "", h.feed.Check(time.Now())
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HoldingHandler handles holdings-related HTTP requests
+type HoldingHandler struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewHoldingHandler creates a new HoldingHandler
+func NewHoldingHandler(db *gorm.DB, logger *slog.Logger) *HoldingHandler {
+	return &HoldingHandler{db: db, logger: logger.With("component", "holding")}
+}
+
+// GetUserHoldings retrieves a user's per-asset balances
+// @Summary      Get user holdings
+// @Description  Get the per-asset balances of a user, including the amount reserved by pending sells and transfers. Non-admins can only access their own holdings.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {array}   models.Holding
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /users/{id}/holdings [get]
+func (h *HoldingHandler) GetUserHoldings(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid user ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
+		return
+	}
+
+	h.logger.DebugContext(c, "GetUserHoldings request", "user_id", id)
+
+	if !canAccessUser(c, uint(id)) {
+		h.logger.InfoContext(c, "Caller may not access holdings, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
+
+	var holdings []models.Holding
+	if err := h.db.WithContext(c.Request.Context()).Preload("Asset").Where("user_id = ?", uint(id)).Order("asset_id").Find(&holdings).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving holdings", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve holdings")
+		return
+	}
+
+	for i := range holdings {
+		holdings[i].Available = holdings[i].Quantity.Sub(holdings[i].Reserved)
+	}
+
+	h.logger.InfoContext(c, "Retrieved holdings", "user_id", id, "count", len(holdings))
+	c.JSON(http.StatusOK, holdings)
+}
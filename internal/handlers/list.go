@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-api-test1/internal/listquery"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userListSpec whitelists the filters and sort keys of GET /users
+var userListSpec = listquery.Spec{
+	Filters: map[string]listquery.Filter{
+		"role":      {Column: "role"},
+		"is_active": {Column: "is_active"},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"username":   "username",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+}
+
+// assetListSpec whitelists the filters and sort keys of GET /assets
+var assetListSpec = listquery.Spec{
+	Filters: map[string]listquery.Filter{
+		"type":      {Column: "type"},
+		"symbol":    {Column: "symbol"},
+		"is_active": {Column: "is_active"},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"symbol":     "symbol",
+		"type":       "type",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id",
+}
+
+// transactionListSpec whitelists the filters and sort keys of GET /transactions
+var transactionListSpec = listquery.Spec{
+	Filters: map[string]listquery.Filter{
+		"asset_id":     {Column: "asset_id"},
+		"user_id":      {Column: "user_id"},
+		"type":         {Column: "type"},
+		"status":       {Column: "status"},
+		"created_from": {Column: "created_at", Kind: listquery.FilterFrom},
+		"created_to":   {Column: "created_at", Kind: listquery.FilterTo},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"type":       "type",
+		"status":     "status",
+		"asset_id":   "asset_id",
+	},
+	DefaultSort: "-created_at",
+}
+
+// priceListSpec whitelists the filters and sort keys of GET /assets/{id}/prices
+var priceListSpec = listquery.Spec{
+	Filters: map[string]listquery.Filter{
+		"source": {Column: "source"},
+		"from":   {Column: "recorded_at", Kind: listquery.FilterFrom},
+		"to":     {Column: "recorded_at", Kind: listquery.FilterTo},
+	},
+	Sorts: map[string]string{
+		"id":          "id",
+		"recorded_at": "recorded_at",
+	},
+	DefaultSort: "-recorded_at",
+}
+
+// parseListQuery parses the list parameters of a request and writes the error
+// response if they are invalid
+func parseListQuery(c *gin.Context, logger *slog.Logger, db *gorm.DB, model interface{}, spec listquery.Spec) (*listquery.Query, bool) {
+	query, err := listquery.Parse(c, db, model, spec)
+	if err != nil {
+		if errors.Is(err, listquery.ErrInvalidQuery) {
+			logger.InfoContext(c, "Invalid list query", "query", c.Request.URL.RawQuery, "error", err)
+			respondError(c, http.StatusBadRequest, "Invalid query", err.Error())
+			return nil, false
+		}
+		logger.ErrorContext(c, "Failed to prepare list query", "error", err)
+		respondError(c, http.StatusInternalServerError, "Internal error", "Failed to prepare query")
+		return nil, false
+	}
+	return query, true
+}
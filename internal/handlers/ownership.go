@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"go-api-test1/internal/auth"
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// canAccessUser reports whether the caller may view or modify the given user record.
+// Non-admins may only access their own record.
+func canAccessUser(c *gin.Context, userID uint) bool {
+	principal, ok := auth.FromContext(c)
+	return ok && (principal.IsAdmin() || principal.UserID == userID)
+}
+
+// ownedTransactions scopes a transaction query to the caller's own transactions
+// unless the caller is an admin
+func ownedTransactions(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal, ok := auth.FromContext(c)
+		if !ok {
+			return db.Where("1 = 0")
+		}
+		if principal.IsAdmin() {
+			return db
+		}
+		return db.Where("transactions.user_id = ?", principal.UserID)
+	}
+}
+
+// visibleTransactions scopes a transaction query to the transactions the caller
+// sent or received unless the caller is an admin. Only the sender may modify a
+// transaction, so write paths use ownedTransactions instead.
+func visibleTransactions(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal, ok := auth.FromContext(c)
+		if !ok {
+			return db.Where("1 = 0")
+		}
+		if principal.IsAdmin() {
+			return db
+		}
+		return db.Where("(transactions.user_id = ? OR transactions.recipient_id = ?)", principal.UserID, principal.UserID)
+	}
+}
+
+// setTransferDirection marks transfers as incoming when the caller is their
+// recipient and as outgoing otherwise. Recipients only see the sender's username.
+func setTransferDirection(c *gin.Context, transactions ...*models.Transaction) {
+	principal, ok := auth.FromContext(c)
+	for _, t := range transactions {
+		if t.Type != models.TransactionTypeTransfer {
+			continue
+		}
+		t.Direction = models.TransferDirectionOutgoing
+		if ok && t.RecipientID != nil && *t.RecipientID == principal.UserID && t.UserID != principal.UserID {
+			t.Direction = models.TransferDirectionIncoming
+			t.User = models.User{ID: t.User.ID, Username: t.User.Username}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/portfolio"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PortfolioHandler handles portfolio valuation HTTP requests
+type PortfolioHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+// NewPortfolioHandler creates a new PortfolioHandler
+func NewPortfolioHandler(db *gorm.DB, cfg *config.Config, logger *slog.Logger) *PortfolioHandler {
+	return &PortfolioHandler{db: db, cfg: cfg, logger: logger.With("component", "portfolio")}
+}
+
+// GetUserPortfolio values a user's positions and computes their P&L
+// @Summary      Get user portfolio
+// @Description  Value a user's positions and compute average cost and realized and unrealized P&L per asset from their completed transactions. Non-admins can only access their own portfolio.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int     true   "User ID"
+// @Param        method  query     string  false  "Cost-basis method: fifo, lifo or average (default fifo)"
+// @Param        as_of   query     string  false  "Snapshot time, RFC 3339 or YYYY-MM-DD for the end of that day (UTC); values at the last price observed by then"
+// @Success      200  {object}  models.Portfolio
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /users/{id}/portfolio [get]
+func (h *PortfolioHandler) GetUserPortfolio(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid user ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
+		return
+	}
+
+	h.logger.DebugContext(c, "GetUserPortfolio request", "user_id", id)
+
+	var user models.User
+	if !canAccessUser(c, uint(id)) || h.db.WithContext(c.Request.Context()).First(&user, uint(id)).Error != nil {
+		h.logger.InfoContext(c, "User not found or not accessible, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
+
+	method := c.DefaultQuery("method", models.CostBasisFIFO)
+	if !portfolio.Methods[method] {
+		respondError(c, http.StatusBadRequest, "Invalid method", "Method must be one of fifo, lifo, average")
+		return
+	}
+
+	var asOf *time.Time
+	if raw := c.Query("as_of"); raw != "" {
+		t, err := parseTime(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid time", "as_of must be an RFC 3339 time or a YYYY-MM-DD date")
+			return
+		}
+		if len(raw) == len("2006-01-02") {
+			// A date covers the whole day
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		asOf = &t
+	}
+
+	result, err := portfolio.Compute(h.db.WithContext(c.Request.Context()), uint(id), method, asOf, h.cfg.RoundingMode)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error computing portfolio", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to compute portfolio")
+		return
+	}
+
+	h.logger.InfoContext(c, "Computed portfolio", "user_id", id, "positions", len(result.Positions), "method", method)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go-api-test1/internal/pricefeed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PriceFeedHandler reports on the price feed worker
+type PriceFeedHandler struct {
+	feed   *pricefeed.Worker
+	logger *slog.Logger
+}
+
+// NewPriceFeedHandler creates a new PriceFeedHandler; feed is nil when the price feed is disabled
+func NewPriceFeedHandler(feed *pricefeed.Worker, logger *slog.Logger) *PriceFeedHandler {
+	return &PriceFeedHandler{feed: feed, logger: logger.With("component", "price_feed")}
+}
+
+// GetStatus reports the price feed's configuration and ingestion metrics
+// @Summary      Get price feed status
+// @Description  Get the configuration and ingestion metrics of this instance's price feed (admin only)
+// @Tags         assets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  pricefeed.Status
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Router       /price-feed [get]
+func (h *PriceFeedHandler) GetStatus(c *gin.Context) {
+	h.logger.DebugContext(c, "GetStatus request")
+	c.JSON(http.StatusOK, h.feed.Status())
+}
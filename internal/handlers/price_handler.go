@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/prices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultCandles is how many intervals the candles endpoint covers when from is omitted
+const defaultCandles = 100
+
+// PriceHandler handles asset price history HTTP requests
+type PriceHandler struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewPriceHandler creates a new PriceHandler
+func NewPriceHandler(db *gorm.DB, logger *slog.Logger) *PriceHandler {
+	return &PriceHandler{db: db, logger: logger.With("component", "price")}
+}
+
+// GetPrices retrieves the price history of an asset
+// @Summary      Get asset price history
+// @Description  Get a page of an asset's price history, newest first by default
+// @Tags         assets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int     true   "Asset ID"
+// @Param        from    query     string  false  "Only prices recorded at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param        to      query     string  false  "Only prices recorded before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param        source  query     string  false  "Filter by source"
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Number of items to skip"
+// @Param        cursor  query     string  false  "Cursor from a previous page's pagination.next_cursor"
+// @Param        sort    query     string  false  "Comma-separated sort keys, prefix with - for descending: id, recorded_at"
+// @Success      200  {object}  models.ListResponse{data=[]models.AssetPrice}
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /assets/{id}/prices [get]
+func (h *PriceHandler) GetPrices(c *gin.Context) {
+	asset, ok := h.findAsset(c)
+	if !ok {
+		return
+	}
+
+	h.logger.DebugContext(c, "GetPrices request", "asset_id", asset.ID)
+
+	query, ok := parseListQuery(c, h.logger, h.db.WithContext(c.Request.Context()), &models.AssetPrice{}, priceListSpec)
+	if !ok {
+		return
+	}
+
+	var history []models.AssetPrice
+	page, err := query.Find(h.db.WithContext(c.Request.Context()).Where("asset_prices.asset_id = ?", asset.ID), &history)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving prices", "asset_id", asset.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve price history")
+		return
+	}
+
+	h.logger.InfoContext(c, "Retrieved prices", "asset_id", asset.ID, "count", len(history), "total", page.Total)
+	c.JSON(http.StatusOK, models.ListResponse{Data: history, Pagination: page})
+}
+
+// GetCandles aggregates the price history and completed trades of an asset
+// @Summary      Get asset candles
+// @Description  Get OHLC candles of an asset built from its price history and completed buys and sells. Volume is the traded amount. Intervals without prices or trades are omitted.
+// @Tags         assets
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path      int     true   "Asset ID"
+// @Param        interval  query     string  false  "Candle interval: 1m, 1h or 1d (default 1h)"
+// @Param        from      query     string  false  "Start time, RFC 3339 or YYYY-MM-DD (default 100 intervals before to)"
+// @Param        to        query     string  false  "End time, exclusive, RFC 3339 or YYYY-MM-DD (default now)"
+// @Success      200  {object}  models.CandlesResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /assets/{id}/candles [get]
+func (h *PriceHandler) GetCandles(c *gin.Context) {
+	asset, ok := h.findAsset(c)
+	if !ok {
+		return
+	}
+
+	name := c.DefaultQuery("interval", "1h")
+	interval, ok := prices.Intervals[name]
+	if !ok {
+		h.logger.InfoContext(c, "Invalid candle interval", "interval", name)
+		respondError(c, http.StatusBadRequest, "Invalid interval", "Interval must be one of 1m, 1h, 1d")
+		return
+	}
+
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		t, err := parseTime(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid time", "to must be an RFC 3339 time or a YYYY-MM-DD date")
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultCandles * interval).Truncate(interval)
+	if raw := c.Query("from"); raw != "" {
+		t, err := parseTime(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid time", "from must be an RFC 3339 time or a YYYY-MM-DD date")
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, "Invalid time range", "from must be before to")
+		return
+	}
+	if to.Sub(from) > prices.MaxCandles*interval {
+		respondError(c, http.StatusBadRequest, "Invalid time range", "The range may span at most "+strconv.Itoa(prices.MaxCandles)+" intervals")
+		return
+	}
+
+	h.logger.DebugContext(c, "GetCandles request", "asset_id", asset.ID, "interval", name, "from", from, "to", to)
+
+	candles, err := prices.Candles(h.db.WithContext(c.Request.Context()), asset.ID, interval, from, to)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error building candles", "asset_id", asset.ID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to build candles")
+		return
+	}
+	if candles == nil {
+		candles = []models.Candle{}
+	}
+
+	c.JSON(http.StatusOK, models.CandlesResponse{
+		AssetID:  asset.ID,
+		Interval: name,
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Candles:  candles,
+	})
+}
+
+// findAsset loads the asset named by the id path parameter and writes the
+// error response if it does not exist
+func (h *PriceHandler) findAsset(c *gin.Context) (*models.Asset, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid asset ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Asset ID must be a valid number")
+		return nil, false
+	}
+
+	var asset models.Asset
+	if err := h.db.WithContext(c.Request.Context()).First(&asset, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			h.logger.InfoContext(c, "Asset not found", "asset_id", id)
+			respondError(c, http.StatusNotFound, "Asset not found", "The requested asset does not exist")
+			return nil, false
+		}
+		h.logger.ErrorContext(c, "Database error retrieving asset", "asset_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve asset")
+		return nil, false
+	}
+	return &asset, true
+}
+
+// parseTime accepts an RFC 3339 time or a YYYY-MM-DD date (midnight UTC)
+func parseTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
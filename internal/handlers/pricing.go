@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"reflect"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Let binding tags such as `required,min=0` validate decimal fields
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			if d, ok := field.Interface().(money.Decimal); ok {
+				return d.Float64()
+			}
+			return nil
+		}, money.Decimal{})
+	}
+}
+
+// priceTransaction rounds a transaction's amount and price to the asset's
+// scales with mode and recomputes its total value, rounded to the asset's price scale
+func priceTransaction(t *models.Transaction, asset models.Asset, mode money.RoundingMode) {
+	t.Amount = t.Amount.Round(asset.AmountScale(), mode)
+	t.Price = t.Price.Round(asset.PricingScale(), mode)
+	t.TotalValue = t.Amount.Mul(t.Price).Round(asset.PricingScale(), mode)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/export"
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/portfolio"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportHandler handles report HTTP requests
+type ReportHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(db *gorm.DB, cfg *config.Config, logger *slog.Logger) *ReportHandler {
+	return &ReportHandler{db: db, cfg: cfg, logger: logger.With("component", "report")}
+}
+
+// capitalGainsCSVHeader is the header row of the CSV capital gains report
+var capitalGainsCSVHeader = []string{
+	"sell_transaction_id", "buy_transaction_id", "symbol", "quantity", "acquired_at", "sold_at",
+	"proceeds", "cost_basis", "gain", "term",
+}
+
+// GetCapitalGains reports the capital gains a user realized in a tax year
+// @Summary      Get capital gains report
+// @Description  Match a user's sells with their tax lots and report the gains realized in a year, classified as short-term or long-term (held more than one year). Non-admins can only access their own report.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Produce      text/csv
+// @Security     BearerAuth
+// @Param        id      path      int     true   "User ID"
+// @Param        year    query     int     false  "Tax year (default current year, UTC)"
+// @Param        method  query     string  false  "Lot matching method: fifo or lifo (default fifo)"
+// @Param        format  query     string  false  "json (default) or csv"
+// @Success      200  {object}  models.CapitalGainsReport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /users/{id}/reports/capital-gains [get]
+func (h *ReportHandler) GetCapitalGains(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid user ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
+		return
+	}
+
+	h.logger.DebugContext(c, "GetCapitalGains request", "user_id", id)
+
+	var user models.User
+	if !canAccessUser(c, uint(id)) || h.db.WithContext(c.Request.Context()).First(&user, uint(id)).Error != nil {
+		h.logger.InfoContext(c, "User not found or not accessible, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
+
+	year := time.Now().UTC().Year()
+	if raw := c.Query("year"); raw != "" {
+		if year, err = strconv.Atoi(raw); err != nil || year < 1970 || year > 9999 {
+			respondError(c, http.StatusBadRequest, "Invalid year", "Year must be a number between 1970 and 9999")
+			return
+		}
+	}
+
+	method := c.DefaultQuery("method", models.CostBasisFIFO)
+	if !portfolio.LotMethods[method] {
+		respondError(c, http.StatusBadRequest, "Invalid method", "Method must be one of fifo, lifo")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		respondError(c, http.StatusBadRequest, "Invalid format", "Format must be one of json, csv")
+		return
+	}
+
+	// Long histories may take longer to report than the server's write timeout
+	deadline := newWriteDeadline(c, h.cfg.Server.WriteTimeout)
+	report, err := portfolio.CapitalGains(h.db.WithContext(c.Request.Context()), uint(id), year, method, h.cfg.RoundingMode)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error computing capital gains", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to compute capital gains")
+		return
+	}
+
+	h.logger.InfoContext(c, "Computed capital gains", "user_id", id, "year", year, "method", method, "count", len(report.Gains))
+	deadline.extend()
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="capital-gains-%d-%d.csv"`, id, year))
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write(capitalGainsCSVHeader)
+	for _, g := range report.Gains {
+		deadline.extend()
+		buyID, acquiredAt := "", ""
+		if g.BuyTransactionID != nil {
+			buyID = strconv.FormatUint(uint64(*g.BuyTransactionID), 10)
+		}
+		if g.AcquiredAt != nil {
+			acquiredAt = g.AcquiredAt.Format(time.RFC3339)
+		}
+		w.Write([]string{
+			strconv.FormatUint(uint64(g.SellTransactionID), 10), buyID, export.CSVText(g.Symbol), g.Quantity.String(),
+			acquiredAt, g.SoldAt.Format(time.RFC3339),
+			g.Proceeds.String(), g.CostBasis.String(), g.Gain.String(), g.Term,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.logger.ErrorContext(c, "Failed to write capital gains CSV", "user_id", id, "error", err)
+	}
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes an ErrorResponse quoting the request and trace IDs
+func respondError(c *gin.Context, status int, err, message string) {
+	c.JSON(status, models.NewErrorResponse(c, err, message))
+}
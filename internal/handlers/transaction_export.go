@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-api-test1/internal/auth"
+	"go-api-test1/internal/export"
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumns are the columns selected into models.TransactionExport
+const exportColumns = "transactions.id, transactions.created_at, transactions.updated_at, transactions.type, " +
+	"transactions.status, transactions.asset_id, assets.symbol AS asset_symbol, transactions.amount, " +
+	"transactions.price, transactions.total_value, transactions.user_id, transactions.recipient_id, transactions.description"
+
+// ExportTransactions streams the caller's transactions as a file
+// @Summary      Export transactions
+// @Description  Export the transactions the caller can see as CSV, JSON Lines or an OFX investment statement. Takes the same filters and sort as listing but is not paginated; rows are streamed from the database. OFX exports only contain completed transactions.
+// @Tags         transactions
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/x-ofx
+// @Security     BearerAuth
+// @Param        format        query     string  false  "csv (default), jsonl or ofx"
+// @Param        sort          query     string  false  "Comma-separated sort keys, prefix with - for descending: id, created_at, updated_at, type, status, asset_id (default -created_at)"
+// @Param        asset_id      query     int     false  "Filter by asset"
+// @Param        user_id       query     int     false  "Filter by owner"
+// @Param        type          query     string  false  "Filter by type"
+// @Param        status        query     string  false  "Filter by status"
+// @Param        created_from  query     string  false  "Only transactions created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param        created_to    query     string  false  "Only transactions created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Success      200  {file}    file
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /transactions/export [get]
+func (h *TransactionHandler) ExportTransactions(c *gin.Context) {
+	h.logger.DebugContext(c, "ExportTransactions request")
+
+	name := c.DefaultQuery("format", "csv")
+	format, ok := export.Formats[name]
+	if !ok {
+		respondError(c, http.StatusBadRequest, "Invalid format", "Format must be one of csv, jsonl, ofx")
+		return
+	}
+
+	query, ok := parseListQuery(c, h.logger, h.db.WithContext(c.Request.Context()), &models.Transaction{}, transactionListSpec)
+	if !ok {
+		return
+	}
+
+	principal, _ := auth.FromContext(c)
+	statement := export.Statement{AccountID: strconv.FormatUint(uint64(principal.UserID), 10), From: time.Unix(0, 0), To: time.Now()}
+	if id := c.Query("user_id"); id != "" {
+		statement.AccountID = id
+	}
+	if from, err := parseTime(c.Query("created_from")); err == nil {
+		statement.From = from
+	}
+	if to, err := parseTime(c.Query("created_to")); err == nil {
+		statement.To = to
+	}
+
+	db := h.db.WithContext(c.Request.Context()).Scopes(visibleTransactions(c)).
+		Select(exportColumns).
+		Joins("LEFT JOIN assets ON assets.id = transactions.asset_id")
+	if name == "ofx" {
+		db = db.Where("transactions.status = ?", models.TransactionStatusCompleted)
+	}
+	rows, err := query.Rows(db)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error exporting transactions", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to export transactions")
+		return
+	}
+	defer rows.Close()
+
+	// Large exports may take longer than the server's write timeout
+	deadline := newWriteDeadline(c, h.cfg.Server.WriteTimeout)
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions-%s.%s"`, time.Now().UTC().Format("20060102"), format.Extension))
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewWriter(name, c.Writer, statement)
+	if err != nil {
+		h.logger.ErrorContext(c, "Failed to create export writer", "format", name, "error", err)
+		return
+	}
+
+	// The response has started, so failures from here on can only end it early
+	count := 0
+	for rows.Next() {
+		var row models.TransactionExport
+		if err := h.db.WithContext(c.Request.Context()).ScanRows(rows, &row); err != nil {
+			h.logger.ErrorContext(c, "Failed to read transaction for export", "error", err)
+			return
+		}
+		if row.Type == models.TransactionTypeTransfer {
+			row.Direction = models.TransferDirectionOutgoing
+			if row.RecipientID != nil && *row.RecipientID == principal.UserID && row.UserID != principal.UserID {
+				row.Direction = models.TransferDirectionIncoming
+			}
+		}
+		deadline.extend()
+		if err := writer.Write(row); err != nil {
+			h.logger.WarnContext(c, "Export aborted", "rows", count, "error", err)
+			return
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		h.logger.ErrorContext(c, "Export aborted", "rows", count, "error", err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		h.logger.WarnContext(c, "Failed to finish export", "rows", count, "error", err)
+		return
+	}
+
+	h.logger.InfoContext(c, "Exported transactions", "rows", count, "format", name)
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 
+	"go-api-test1/internal/auth"
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/holdings"
+	"go-api-test1/internal/metrics"
 	"go-api-test1/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -13,45 +20,65 @@ import (
 
 // TransactionHandler handles transaction-related HTTP requests
 type TransactionHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	logger *slog.Logger
 }
 
 // NewTransactionHandler creates a new TransactionHandler
-func NewTransactionHandler(db *gorm.DB) *TransactionHandler {
-	return &TransactionHandler{db: db}
+func NewTransactionHandler(db *gorm.DB, cfg *config.Config, logger *slog.Logger) *TransactionHandler {
+	return &TransactionHandler{db: db, cfg: cfg, logger: logger.With("component", "transaction")}
 }
 
 // GetTransactions retrieves all transactions
 // @Summary      Get all transactions
-// @Description  Get a list of all transactions
+// @Description  Get a list of transactions. Non-admins only see their own transactions and transfers they received; transfers carry a direction relative to the caller.
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}  models.Transaction
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Number of items to skip"
+// @Param        cursor  query     string  false  "Cursor from a previous page's pagination.next_cursor"
+// @Param        sort          query     string  false  "Comma-separated sort keys, prefix with - for descending: id, created_at, updated_at, type, status, asset_id (default -created_at)"
+// @Param        asset_id      query     int     false  "Filter by asset"
+// @Param        user_id       query     int     false  "Filter by owner"
+// @Param        type          query     string  false  "Filter by type"
+// @Param        status        query     string  false  "Filter by status"
+// @Param        created_from  query     string  false  "Only transactions created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param        created_to    query     string  false  "Only transactions created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Success      200  {object}  models.ListResponse{data=[]models.Transaction}
+// @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /transactions [get]
 func (h *TransactionHandler) GetTransactions(c *gin.Context) {
-	log.Printf("Transaction: GetTransactions request from %s", c.ClientIP())
+	h.logger.DebugContext(c, "GetTransactions request")
 	
+	query, ok := parseListQuery(c, h.logger, h.db.WithContext(c.Request.Context()), &models.Transaction{}, transactionListSpec)
+	if !ok {
+		return
+	}
+
 	var transactions []models.Transaction
-	if err := h.db.Preload("User").Preload("Asset").Find(&transactions).Error; err != nil {
-		log.Printf("Transaction: Database error retrieving transactions: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve transactions",
-		})
+	page, err := query.Find(h.db.WithContext(c.Request.Context()).Scopes(visibleTransactions(c)), &transactions, "User", "Asset")
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving transactions", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transactions")
 		return
 	}
 
-	log.Printf("Transaction: Successfully retrieved %d transactions", len(transactions))
-	c.JSON(http.StatusOK, transactions)
+	for i := range transactions {
+		setTransferDirection(c, &transactions[i])
+	}
+
+	h.logger.InfoContext(c, "Retrieved transactions", "count", len(transactions), "total", page.Total)
+	c.JSON(http.StatusOK, models.ListResponse{Data: transactions, Pagination: page})
 }
 
 // GetTransaction retrieves a specific transaction by ID
 // @Summary      Get transaction by ID
-// @Description  Get a specific transaction by its ID
+// @Description  Get a specific transaction by its ID. Recipients can view transfers sent to them; other users' transactions are reported as not found for non-admins.
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
@@ -65,133 +92,164 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Transaction: Invalid transaction ID format: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Transaction ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid transaction ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Transaction ID must be a valid number")
 		return
 	}
 
-	log.Printf("Transaction: GetTransaction request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "GetTransaction request", "transaction_id", id)
 
 	var transaction models.Transaction
-	if err := h.db.Preload("User").Preload("Asset").First(&transaction, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).Scopes(visibleTransactions(c)).Preload("User").Preload("Asset").First(&transaction, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Transaction: Transaction not found with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Transaction not found",
-				Message: "The requested transaction does not exist",
-			})
+			h.logger.InfoContext(c, "Transaction not found", "transaction_id", id)
+			respondError(c, http.StatusNotFound, "Transaction not found", "The requested transaction does not exist")
 			return
 		}
-		log.Printf("Transaction: Database error retrieving transaction ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve transaction",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving transaction", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction")
 		return
 	}
 
-	log.Printf("Transaction: Successfully retrieved transaction ID: %d, type: %s, amount: %.2f", transaction.ID, transaction.Type, transaction.Amount)
+	setTransferDirection(c, &transaction)
+
+	h.logger.InfoContext(c, "Retrieved transaction", "transaction_id", transaction.ID, "type", transaction.Type, "amount", transaction.Amount)
 	c.JSON(http.StatusOK, transaction)
 }
 
 // CreateTransaction creates a new transaction
 // @Summary      Create transaction
-// @Description  Create a new transaction
+// @Description  Create a new transaction. Transfers require a recipient, by ID or username, who is credited when the transfer completes.
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        transaction body      models.CreateTransactionRequest  true  "Transaction data"
+// @Param        Idempotency-Key  header  string  false  "Makes retries of this request safe; the first response is replayed"
 // @Success      201  {object}  models.Transaction
 // @Failure      400  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /transactions [post]
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
-	log.Printf("Transaction: CreateTransaction request from %s", c.ClientIP())
+	h.logger.DebugContext(c, "CreateTransaction request")
 	
 	var createReq models.CreateTransactionRequest
 	if err := c.ShouldBindJSON(&createReq); err != nil {
-		log.Printf("Transaction: Invalid create request from %s: %v", c.ClientIP(), err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid create request", "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	// Get user ID from JWT token
-	userID, exists := c.Get("user_id")
-	if !exists {
-		log.Printf("Transaction: User ID not found in token from %s", c.ClientIP())
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error:   "Unauthorized",
-			Message: "User ID not found in token",
-		})
+	// Get the authenticated caller
+	principal, ok := auth.FromContext(c)
+	if !ok {
+		h.logger.WarnContext(c, "User ID not found in token")
+		respondError(c, http.StatusUnauthorized, "Unauthorized", "User ID not found in token")
 		return
 	}
 
-	log.Printf("Transaction: Creating transaction for user ID: %v, asset ID: %d, type: %s, amount: %.2f", 
-		userID, createReq.AssetID, createReq.Type, createReq.Amount)
+	h.logger.InfoContext(c, "Creating transaction", "user_id", principal.UserID,
+		"asset_id", createReq.AssetID, "type", createReq.Type, "amount", createReq.Amount)
 
 	// Verify asset exists
 	var asset models.Asset
-	if err := h.db.First(&asset, createReq.AssetID).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&asset, createReq.AssetID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Transaction: Asset not found with ID: %d", createReq.AssetID)
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error:   "Asset not found",
-				Message: "The specified asset does not exist",
-			})
+			h.logger.InfoContext(c, "Asset not found", "asset_id", createReq.AssetID)
+			respondError(c, http.StatusBadRequest, "Asset not found", "The specified asset does not exist")
 			return
 		}
-		log.Printf("Transaction: Database error verifying asset ID: %d: %v", createReq.AssetID, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to verify asset",
-		})
+		h.logger.ErrorContext(c, "Database error verifying asset", "asset_id", createReq.AssetID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to verify asset")
 		return
 	}
 
-	log.Printf("Transaction: Asset verified - ID: %d, name: %s", asset.ID, asset.Name)
-
-	// Calculate total value
-	totalValue := createReq.Amount * createReq.Price
-	log.Printf("Transaction: Calculated total value: %.2f (amount: %.2f * price: %.2f)", totalValue, createReq.Amount, createReq.Price)
+	h.logger.DebugContext(c, "Asset verified", "asset_id", asset.ID, "name", asset.Name)
+
+	// Transfers must name another active user as recipient; other types must not
+	var recipientID *uint
+	if createReq.Type == models.TransactionTypeTransfer {
+		recipient, err := h.findRecipient(c.Request.Context(), createReq)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				h.logger.InfoContext(c, "Recipient not found", "recipient_id", createReq.RecipientID, "recipient_username", createReq.RecipientUsername)
+				respondError(c, http.StatusBadRequest, "Recipient not found", "The specified recipient does not exist")
+				return
+			}
+			h.logger.ErrorContext(c, "Database error verifying recipient", "error", err)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to verify recipient")
+			return
+		}
+		if recipient.ID == principal.UserID {
+			h.logger.InfoContext(c, "User attempted a transfer to themselves", "user_id", principal.UserID)
+			respondError(c, http.StatusBadRequest, "Invalid recipient", "Cannot transfer to yourself")
+			return
+		}
+		recipientID = &recipient.ID
+		h.logger.DebugContext(c, "Recipient verified", "recipient_id", recipient.ID, "recipient_username", recipient.Username)
+	} else if createReq.RecipientID != 0 || createReq.RecipientUsername != "" {
+		h.logger.InfoContext(c, "Recipient given for a non-transfer transaction", "type", createReq.Type, "user_id", principal.UserID)
+		respondError(c, http.StatusBadRequest, "Invalid request", "Only transfers have a recipient")
+		return
+	}
 
 	transaction := models.Transaction{
-		UserID:      userID.(uint),
+		UserID:      principal.UserID,
 		AssetID:     createReq.AssetID,
 		Type:        createReq.Type,
 		Amount:      createReq.Amount,
 		Price:       createReq.Price,
-		TotalValue:  totalValue,
-		Status:      "pending",
+		Status:      models.TransactionStatusPending,
 		Description: createReq.Description,
+		RecipientID: recipientID,
+	}
+
+	// Round to the asset's precision and calculate total value
+	priceTransaction(&transaction, asset, h.cfg.RoundingMode)
+	h.logger.DebugContext(c, "Calculated total value", "total_value", transaction.TotalValue, "amount", transaction.Amount, "price", transaction.Price)
+
+	if !transaction.Amount.IsPositive() {
+		h.logger.InfoContext(c, "Amount rounds to zero", "amount", createReq.Amount, "asset_id", asset.ID)
+		respondError(c, http.StatusBadRequest, "Invalid request", "Amount must be greater than zero at the asset's precision")
+		return
 	}
 
-	if err := h.db.Create(&transaction).Error; err != nil {
-		log.Printf("Transaction: Database error creating transaction: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to create transaction",
-		})
+	// Sells and transfers reserve the balance they will debit when they complete
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		if err := holdings.ReserveFor(tx, &transaction); err != nil {
+			return err
+		}
+		return recordStatusChange(tx, transaction.ID, "", transaction.Status, principal.UserID, "")
+	})
+	if err != nil {
+		if errors.Is(err, holdings.ErrInsufficientBalance) {
+			h.logger.InfoContext(c, "Insufficient balance", "user_id", principal.UserID, "asset_id", createReq.AssetID, "amount", transaction.Amount)
+			respondError(c, http.StatusUnprocessableEntity, "Insufficient balance", "The amount exceeds your available balance for this asset")
+			return
+		}
+		h.logger.ErrorContext(c, "Database error creating transaction", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to create transaction")
 		return
 	}
 
-	log.Printf("Transaction: Successfully created transaction ID: %d for user ID: %v", transaction.ID, userID)
+	h.logger.InfoContext(c, "Created transaction", "transaction_id", transaction.ID, "user_id", principal.UserID)
+	metrics.TransactionsCreated.WithLabelValues(transaction.Type, transaction.Status).Inc()
 
 	// Load the created transaction with relationships
-	h.db.Preload("User").Preload("Asset").First(&transaction, transaction.ID)
+	h.db.WithContext(c.Request.Context()).Preload("User").Preload("Asset").First(&transaction, transaction.ID)
+	setTransferDirection(c, &transaction)
 
 	c.JSON(http.StatusCreated, transaction)
 }
 
 // UpdateTransaction updates a specific transaction
 // @Summary      Update transaction
-// @Description  Update a specific transaction by its ID
+// @Description  Update a specific transaction by its ID. Type, amount and price can only be changed while the transaction is pending; status changes follow the transaction state machine. Other users' transactions are reported as not found for non-admins.
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
@@ -200,96 +258,165 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 // @Param        transaction body      models.UpdateTransactionRequest  true  "Transaction update data"
 // @Success      200  {object}  models.Transaction
 // @Failure      400  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
 // @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /transactions/{id} [put]
 func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Transaction: Invalid transaction ID format for update: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Transaction ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid transaction ID format for update", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Transaction ID must be a valid number")
 		return
 	}
 
-	log.Printf("Transaction: UpdateTransaction request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "UpdateTransaction request", "transaction_id", id)
 
 	var transaction models.Transaction
-	if err := h.db.First(&transaction, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).Scopes(ownedTransactions(c)).First(&transaction, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Transaction: Transaction not found for update with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Transaction not found",
-				Message: "The requested transaction does not exist",
-			})
+			h.logger.InfoContext(c, "Transaction not found for update", "transaction_id", id)
+			respondError(c, http.StatusNotFound, "Transaction not found", "The requested transaction does not exist")
 			return
 		}
-		log.Printf("Transaction: Database error retrieving transaction for update ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve transaction",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving transaction for update", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction")
 		return
 	}
 
 	var updateReq models.UpdateTransactionRequest
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		log.Printf("Transaction: Invalid update request for transaction ID: %d: %v", id, err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid update request", "transaction_id", id, "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("Transaction: Updating transaction ID: %d with fields: type=%s, amount=%.2f, price=%.2f, status=%s", 
-		id, updateReq.Type, updateReq.Amount, updateReq.Price, updateReq.Status)
+	h.logger.InfoContext(c, "Updating transaction", "transaction_id", id,
+		"type", updateReq.Type, "amount", updateReq.Amount, "price", updateReq.Price, "status", updateReq.Status)
+
+	principal, ok := auth.FromContext(c)
+	if !ok {
+		h.logger.WarnContext(c, "User ID not found in token")
+		respondError(c, http.StatusUnauthorized, "Unauthorized", "User ID not found in token")
+		return
+	}
+
+	// A transfer's recipient is fixed at creation
+	if updateReq.Type != "" && (updateReq.Type == models.TransactionTypeTransfer) != (transaction.Type == models.TransactionTypeTransfer) {
+		h.logger.InfoContext(c, "Rejecting type change", "transaction_id", id, "from", transaction.Type, "to", updateReq.Type)
+		respondError(c, http.StatusBadRequest, "Invalid request", "A transaction cannot be changed to or from a transfer")
+		return
+	}
+
+	financialChange := updateReq.Type != "" || updateReq.Amount.IsPositive() || updateReq.Price.IsPositive()
+	statusChange := updateReq.Status != "" && updateReq.Status != transaction.Status
+
+	// Financial fields are frozen once a transaction leaves pending
+	if financialChange && transaction.Status != models.TransactionStatusPending {
+		h.logger.InfoContext(c, "Rejecting change of financial fields on non-pending transaction", "transaction_id", id, "status", transaction.Status)
+		respondError(c, http.StatusConflict, "Transaction not pending", "Type, amount and price can only be changed while a transaction is pending")
+		return
+	}
+
+	if statusChange {
+		if !canTransition(transaction.Status, updateReq.Status) {
+			h.logger.InfoContext(c, "Invalid status transition", "transaction_id", id, "from", transaction.Status, "to", updateReq.Status)
+			respondError(c, http.StatusConflict, "Invalid status transition", "Cannot change status from "+transaction.Status+" to "+updateReq.Status)
+			return
+		}
+		if updateReq.Status != models.TransactionStatusCancelled && !principal.Can(auth.PermTransactionsManage) {
+			h.logger.InfoContext(c, "User may not set status", "user_id", principal.UserID, "status", updateReq.Status, "transaction_id", id)
+			respondError(c, http.StatusForbidden, "Forbidden", "Only operators can complete or fail transactions")
+			return
+		}
+	}
+	previous := transaction
 
-	// Update fields if provided
+	// Update fields if provided, collecting the columns that change
+	changes := map[string]interface{}{}
 	if updateReq.Type != "" {
 		transaction.Type = updateReq.Type
+		changes["type"] = transaction.Type
 	}
-	if updateReq.Amount > 0 {
+	if updateReq.Amount.IsPositive() {
 		transaction.Amount = updateReq.Amount
 	}
-	if updateReq.Price > 0 {
+	if updateReq.Price.IsPositive() {
 		transaction.Price = updateReq.Price
 	}
-	if updateReq.Status != "" {
-		transaction.Status = updateReq.Status
-	}
 	if updateReq.Description != "" {
 		transaction.Description = updateReq.Description
+		changes["description"] = transaction.Description
 	}
 
 	// Recalculate total value if amount or price changed
-	if updateReq.Amount > 0 || updateReq.Price > 0 {
-		transaction.TotalValue = transaction.Amount * transaction.Price
-		log.Printf("Transaction: Recalculated total value: %.2f", transaction.TotalValue)
+	if updateReq.Amount.IsPositive() || updateReq.Price.IsPositive() {
+		var asset models.Asset
+		if err := h.db.WithContext(c.Request.Context()).First(&asset, transaction.AssetID).Error; err != nil {
+			h.logger.ErrorContext(c, "Database error retrieving asset", "asset_id", transaction.AssetID, "transaction_id", id, "error", err)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve asset")
+			return
+		}
+		priceTransaction(&transaction, asset, h.cfg.RoundingMode)
+		if !transaction.Amount.IsPositive() {
+			h.logger.InfoContext(c, "Amount rounds to zero", "amount", updateReq.Amount, "asset_id", asset.ID)
+			respondError(c, http.StatusBadRequest, "Invalid request", "Amount must be greater than zero at the asset's precision")
+			return
+		}
+		changes["amount"] = transaction.Amount
+		changes["price"] = transaction.Price
+		changes["total_value"] = transaction.TotalValue
+		h.logger.DebugContext(c, "Recalculated total value", "total_value", transaction.TotalValue)
 	}
 
-	if err := h.db.Save(&transaction).Error; err != nil {
-		log.Printf("Transaction: Database error updating transaction ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to update transaction",
-		})
+	// Save the changed columns only if the transaction is still the version
+	// that was checked, then swap the old reservation for the new one and
+	// apply any status change, all atomically
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if len(changes) > 0 {
+			changes["version"] = gorm.Expr("version + 1")
+			result := tx.Model(&models.Transaction{}).
+				Where("id = ? AND status = ? AND version = ?", transaction.ID, previous.Status, previous.Version).
+				Updates(changes)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: transaction %d is no longer version %d", errTransactionChanged, transaction.ID, previous.Version)
+			}
+		}
+		if financialChange {
+			if err := holdings.ReleaseFor(tx, &previous); err != nil {
+				return err
+			}
+			if err := holdings.ReserveFor(tx, &transaction); err != nil {
+				return err
+			}
+		}
+		if statusChange {
+			return applyStatusChange(tx, &transaction, updateReq.Status, principal.UserID, "")
+		}
+		return nil
+	})
+	if err != nil {
+		h.respondStatusChangeError(c, uint(id), err)
 		return
 	}
 
-	log.Printf("Transaction: Successfully updated transaction ID: %d", transaction.ID)
+	h.logger.InfoContext(c, "Updated transaction", "transaction_id", transaction.ID)
 
 	// Load the updated transaction with relationships
-	h.db.Preload("User").Preload("Asset").First(&transaction, transaction.ID)
+	h.db.WithContext(c.Request.Context()).Preload("User").Preload("Asset").First(&transaction, transaction.ID)
+	setTransferDirection(c, &transaction)
 
 	c.JSON(http.StatusOK, transaction)
 }
 
 // DeleteTransaction deletes a specific transaction
 // @Summary      Delete transaction
-// @Description  Delete a specific transaction by its ID
+// @Description  Delete a specific transaction by its ID. Other users' transactions are reported as not found for non-admins.
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
@@ -298,50 +425,271 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /transactions/{id} [delete]
 func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("Transaction: Invalid transaction ID format for delete: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "Transaction ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid transaction ID format for delete", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Transaction ID must be a valid number")
 		return
 	}
 
-	log.Printf("Transaction: DeleteTransaction request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "DeleteTransaction request", "transaction_id", id)
 
 	var transaction models.Transaction
-	if err := h.db.First(&transaction, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).Scopes(ownedTransactions(c)).First(&transaction, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("Transaction: Transaction not found for delete with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "Transaction not found",
-				Message: "The requested transaction does not exist",
-			})
+			h.logger.InfoContext(c, "Transaction not found for delete", "transaction_id", id)
+			respondError(c, http.StatusNotFound, "Transaction not found", "The requested transaction does not exist")
 			return
 		}
-		log.Printf("Transaction: Database error retrieving transaction for delete ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve transaction",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving transaction for delete", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction")
 		return
 	}
 
-	log.Printf("Transaction: Deleting transaction ID: %d, type: %s, amount: %.2f", transaction.ID, transaction.Type, transaction.Amount)
+	h.logger.InfoContext(c, "Deleting transaction", "transaction_id", transaction.ID, "type", transaction.Type, "amount", transaction.Amount)
 
-	if err := h.db.Delete(&transaction).Error; err != nil {
-		log.Printf("Transaction: Database error deleting transaction ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to delete transaction",
-		})
+	// Completed transactions are part of the holdings ledger and must stay
+	if transaction.Status == models.TransactionStatusCompleted {
+		h.logger.InfoContext(c, "Rejecting delete of completed transaction", "transaction_id", id)
+		respondError(c, http.StatusConflict, "Transaction completed", "Completed transactions cannot be deleted")
+		return
+	}
+
+	// Delete the transaction only if it is still the version that was checked,
+	// so that it cannot have completed or had its reservation changed meanwhile
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("status = ? AND version = ?", transaction.Status, transaction.Version).Delete(&transaction)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: transaction %d is no longer version %d", errTransactionChanged, transaction.ID, transaction.Version)
+		}
+		if transaction.Status == models.TransactionStatusPending {
+			return holdings.ReleaseFor(tx, &transaction)
+		}
+		return nil
+	})
+	if errors.Is(err, errTransactionChanged) {
+		h.logger.InfoContext(c, "Transaction changed before delete", "transaction_id", id, "error", err)
+		respondError(c, http.StatusConflict, "Transaction changed", "The transaction was changed by another request; reload it and try again")
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error deleting transaction", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to delete transaction")
 		return
 	}
 
-	log.Printf("Transaction: Successfully deleted transaction ID: %d", transaction.ID)
+	h.logger.InfoContext(c, "Deleted transaction", "transaction_id", transaction.ID)
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
 }
+
+// CancelTransaction cancels a pending transaction
+// @Summary      Cancel transaction
+// @Description  Cancel a pending transaction and release any balance it reserved. Other users' transactions are reported as not found for non-admins.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int                              true   "Transaction ID"
+// @Param        action  body      models.TransactionActionRequest  false  "Reason for the change"
+// @Success      200  {object}  models.Transaction
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /transactions/{id}/cancel [post]
+func (h *TransactionHandler) CancelTransaction(c *gin.Context) {
+	h.changeStatus(c, models.TransactionStatusCancelled, true)
+}
+
+// CompleteTransaction completes a pending transaction
+// @Summary      Complete transaction
+// @Description  Mark a pending transaction as completed and apply it to the user's holdings. Requires the operator or admin role.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int                              true   "Transaction ID"
+// @Param        action  body      models.TransactionActionRequest  false  "Reason for the change"
+// @Success      200  {object}  models.Transaction
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /transactions/{id}/complete [post]
+func (h *TransactionHandler) CompleteTransaction(c *gin.Context) {
+	h.changeStatus(c, models.TransactionStatusCompleted, false)
+}
+
+// FailTransaction marks a pending transaction as failed
+// @Summary      Fail transaction
+// @Description  Mark a pending transaction as failed and release any balance it reserved. Requires the operator or admin role.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      int                              true   "Transaction ID"
+// @Param        action  body      models.TransactionActionRequest  false  "Reason for the change"
+// @Success      200  {object}  models.Transaction
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /transactions/{id}/fail [post]
+func (h *TransactionHandler) FailTransaction(c *gin.Context) {
+	h.changeStatus(c, models.TransactionStatusFailed, false)
+}
+
+// GetTransactionHistory retrieves the status history of a transaction
+// @Summary      Get transaction status history
+// @Description  Get every status change of a transaction, oldest first, including who made it. Recipients can view the history of transfers sent to them; other users' transactions are reported as not found for non-admins.
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Transaction ID"
+// @Success      200  {array}   models.TransactionStatusChange
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /transactions/{id}/history [get]
+func (h *TransactionHandler) GetTransactionHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid transaction ID format for history", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Transaction ID must be a valid number")
+		return
+	}
+
+	h.logger.DebugContext(c, "GetTransactionHistory request", "transaction_id", id)
+
+	var transaction models.Transaction
+	if err := h.db.WithContext(c.Request.Context()).Scopes(visibleTransactions(c)).First(&transaction, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			h.logger.InfoContext(c, "Transaction not found for history", "transaction_id", id)
+			respondError(c, http.StatusNotFound, "Transaction not found", "The requested transaction does not exist")
+			return
+		}
+		h.logger.ErrorContext(c, "Database error retrieving transaction for history", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction")
+		return
+	}
+
+	var history []models.TransactionStatusChange
+	if err := h.db.WithContext(c.Request.Context()).Where("transaction_id = ?", transaction.ID).Order("created_at, id").Find(&history).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving history", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction history")
+		return
+	}
+
+	h.logger.InfoContext(c, "Retrieved status changes", "transaction_id", id, "count", len(history))
+	c.JSON(http.StatusOK, history)
+}
+
+// changeStatus implements the status action endpoints. When ownedOnly is set,
+// non-admins can only act on their own transactions.
+func (h *TransactionHandler) changeStatus(c *gin.Context, to string, ownedOnly bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.logger.InfoContext(c, "Invalid transaction ID format for status change", "status", to, "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "Transaction ID must be a valid number")
+		return
+	}
+
+	principal, ok := auth.FromContext(c)
+	if !ok {
+		h.logger.WarnContext(c, "User ID not found in token")
+		respondError(c, http.StatusUnauthorized, "Unauthorized", "User ID not found in token")
+		return
+	}
+	h.logger.DebugContext(c, "Status change requested", "transaction_id", id, "status", to, "user_id", principal.UserID)
+
+	var actionReq models.TransactionActionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&actionReq); err != nil {
+			h.logger.InfoContext(c, "Invalid status change request", "transaction_id", id, "error", err)
+			respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
+			return
+		}
+	}
+
+	query := h.db.WithContext(c.Request.Context())
+	if ownedOnly {
+		query = query.Scopes(ownedTransactions(c))
+	}
+
+	var transaction models.Transaction
+	if err := query.First(&transaction, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			h.logger.InfoContext(c, "Transaction not found for status change", "transaction_id", id)
+			respondError(c, http.StatusNotFound, "Transaction not found", "The requested transaction does not exist")
+			return
+		}
+		h.logger.ErrorContext(c, "Database error retrieving transaction for status change", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve transaction")
+		return
+	}
+
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return applyStatusChange(tx, &transaction, to, principal.UserID, actionReq.Reason)
+	})
+	if err != nil {
+		h.respondStatusChangeError(c, uint(id), err)
+		return
+	}
+
+	h.logger.InfoContext(c, "Changed transaction status", "transaction_id", transaction.ID, "status", transaction.Status)
+
+	// Load the transaction with relationships
+	h.db.WithContext(c.Request.Context()).Preload("User").Preload("Asset").First(&transaction, transaction.ID)
+	setTransferDirection(c, &transaction)
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// respondStatusChangeError writes the response for a failed transaction update or status change
+func (h *TransactionHandler) respondStatusChangeError(c *gin.Context, id uint, err error) {
+	switch {
+	case errors.Is(err, errInvalidTransition):
+		h.logger.InfoContext(c, "Invalid status transition", "transaction_id", id, "error", err)
+		respondError(c, http.StatusConflict, "Invalid status transition", err.Error())
+	case errors.Is(err, errTransactionChanged):
+		h.logger.InfoContext(c, "Transaction changed concurrently", "transaction_id", id, "error", err)
+		respondError(c, http.StatusConflict, "Transaction changed", "The transaction was changed by another request; reload it and try again")
+	case errors.Is(err, holdings.ErrInsufficientBalance):
+		h.logger.InfoContext(c, "Insufficient balance to update transaction", "transaction_id", id)
+		respondError(c, http.StatusUnprocessableEntity, "Insufficient balance", "The amount exceeds the available balance for this asset")
+	default:
+		h.logger.ErrorContext(c, "Database error updating transaction", "transaction_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to update transaction")
+	}
+}
+
+// findRecipient looks up the active recipient of a transfer by ID or username.
+// It returns gorm.ErrRecordNotFound if neither is given or no such user exists.
+func (h *TransactionHandler) findRecipient(ctx context.Context, req models.CreateTransactionRequest) (*models.User, error) {
+	query := h.db.WithContext(ctx).Where("is_active = ?", true)
+	switch {
+	case req.RecipientID != 0:
+		query = query.Where("id = ?", req.RecipientID)
+	case req.RecipientUsername != "":
+		query = query.Where("username = ?", req.RecipientUsername)
+	default:
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var recipient models.User
+	if err := query.First(&recipient).Error; err != nil {
+		return nil, err
+	}
+	return &recipient, nil
+}
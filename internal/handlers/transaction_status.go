@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"go-api-test1/internal/holdings"
+	"go-api-test1/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// errInvalidTransition is returned when a status change is not allowed by the state machine
+var errInvalidTransition = errors.New("invalid status transition")
+
+// errTransactionChanged is returned when a transaction was changed by another
+// request between being read and being updated
+var errTransactionChanged = errors.New("transaction was changed concurrently")
+
+// transactionTransitions lists the statuses each status may move to.
+// Completed, failed and cancelled are terminal.
+var transactionTransitions = map[string][]string{
+	models.TransactionStatusPending: {
+		models.TransactionStatusCompleted,
+		models.TransactionStatusFailed,
+		models.TransactionStatusCancelled,
+	},
+}
+
+// canTransition reports whether a transaction may move from one status to another
+func canTransition(from, to string) bool {
+	for _, next := range transactionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// applyStatusChange moves a transaction to a new status inside the database
+// transaction tx. It settles or releases the transaction's holdings, updates the
+// status only if nobody else changed it in the meantime, and records the change
+// in the status history.
+func applyStatusChange(tx *gorm.DB, t *models.Transaction, to string, changedByID uint, reason string) error {
+	from := t.Status
+	if !canTransition(from, to) {
+		return fmt.Errorf("%w: cannot change status from %s to %s", errInvalidTransition, from, to)
+	}
+
+	switch to {
+	case models.TransactionStatusCompleted:
+		if err := holdings.Settle(tx, t); err != nil {
+			return err
+		}
+	case models.TransactionStatusFailed, models.TransactionStatusCancelled:
+		if err := holdings.ReleaseFor(tx, t); err != nil {
+			return err
+		}
+	}
+
+	result := tx.Model(&models.Transaction{}).
+		Where("id = ? AND status = ?", t.ID, from).
+		Updates(map[string]interface{}{"status": to, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: transaction %d is no longer %s", errInvalidTransition, t.ID, from)
+	}
+	t.Status = to
+
+	return recordStatusChange(tx, t.ID, from, to, changedByID, reason)
+}
+
+// recordStatusChange appends an entry to a transaction's status history
+func recordStatusChange(tx *gorm.DB, transactionID uint, from, to string, changedByID uint, reason string) error {
+	return tx.Create(&models.TransactionStatusChange{
+		TransactionID: transactionID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ChangedByID:   changedByID,
+		Reason:        reason,
+	}).Error
+}
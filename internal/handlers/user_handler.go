@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 
+	"go-api-test1/internal/auth"
+	"go-api-test1/internal/config"
 	"go-api-test1/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -13,45 +15,58 @@ import (
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	revocations *auth.RevocationStore
+	cfg         *config.Config
+	logger      *slog.Logger
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, revocations *auth.RevocationStore, cfg *config.Config, logger *slog.Logger) *UserHandler {
+	return &UserHandler{db: db, revocations: revocations, cfg: cfg, logger: logger.With("component", "user")}
 }
 
 // GetUsers retrieves all users
 // @Summary      Get all users
-// @Description  Get a list of all users
+// @Description  Get a page of users
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}  models.User
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Number of items to skip"
+// @Param        cursor  query     string  false  "Cursor from a previous page's pagination.next_cursor"
+// @Param        sort       query     string  false  "Comma-separated sort keys, prefix with - for descending: id, username, email, created_at"
+// @Param        role       query     string  false  "Filter by role"
+// @Param        is_active  query     bool    false  "Filter by active flag"
+// @Success      200  {object}  models.ListResponse{data=[]models.User}
+// @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	log.Printf("User: GetUsers request from %s", c.ClientIP())
+	h.logger.DebugContext(c, "GetUsers request")
 	
+	query, ok := parseListQuery(c, h.logger, h.db.WithContext(c.Request.Context()), &models.User{}, userListSpec)
+	if !ok {
+		return
+	}
+
 	var users []models.User
-	if err := h.db.Find(&users).Error; err != nil {
-		log.Printf("User: Database error retrieving users: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve users",
-		})
+	page, err := query.Find(h.db.WithContext(c.Request.Context()), &users)
+	if err != nil {
+		h.logger.ErrorContext(c, "Database error retrieving users", "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve users")
 		return
 	}
 
-	log.Printf("User: Successfully retrieved %d users", len(users))
-	c.JSON(http.StatusOK, users)
+	h.logger.InfoContext(c, "Retrieved users", "count", len(users), "total", page.Total)
+	c.JSON(http.StatusOK, models.ListResponse{Data: users, Pagination: page})
 }
 
 // GetUser retrieves a specific user by ID
 // @Summary      Get user by ID
-// @Description  Get a specific user by their ID
+// @Description  Get a specific user by their ID. Non-admins can only access their own record.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -65,41 +80,39 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("User: Invalid user ID format: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "User ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid user ID format", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
 		return
 	}
 
-	log.Printf("User: GetUser request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "GetUser request", "user_id", id)
+
+	// Other users' records are reported as missing to avoid enumeration
+	if !canAccessUser(c, uint(id)) {
+		h.logger.InfoContext(c, "Caller may not access user, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
 
 	var user models.User
-	if err := h.db.First(&user, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&user, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("User: User not found with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "User not found",
-				Message: "The requested user does not exist",
-			})
+			h.logger.InfoContext(c, "User not found", "user_id", id)
+			respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
 			return
 		}
-		log.Printf("User: Database error retrieving user ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve user",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving user", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve user")
 		return
 	}
 
-	log.Printf("User: Successfully retrieved user ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Retrieved user", "user_id", user.ID, "email", user.Email)
 	c.JSON(http.StatusOK, user)
 }
 
 // UpdateUser updates a specific user
 // @Summary      Update user
-// @Description  Update a specific user by their ID
+// @Description  Update a specific user by their ID. Non-admins can only update their own record.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -114,46 +127,41 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("User: Invalid user ID format for update: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "User ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid user ID format for update", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
 		return
 	}
 
-	log.Printf("User: UpdateUser request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "UpdateUser request", "user_id", id)
+
+	// Other users' records are reported as missing to avoid enumeration
+	if !canAccessUser(c, uint(id)) {
+		h.logger.InfoContext(c, "Caller may not access user, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
 
 	var user models.User
-	if err := h.db.First(&user, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&user, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("User: User not found for update with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "User not found",
-				Message: "The requested user does not exist",
-			})
+			h.logger.InfoContext(c, "User not found for update", "user_id", id)
+			respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
 			return
 		}
-		log.Printf("User: Database error retrieving user for update ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve user",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving user for update", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve user")
 		return
 	}
 
 	var updateReq models.UpdateUserRequest
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		log.Printf("User: Invalid update request for user ID: %d: %v", id, err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: err.Error(),
-		})
+		h.logger.InfoContext(c, "Invalid update request", "user_id", id, "error", err)
+		respondError(c, http.StatusBadRequest, "Invalid request", err.Error())
 		return
 	}
 
-	log.Printf("User: Updating user ID: %d with fields: email=%s, username=%s, firstName=%s, lastName=%s", 
-		id, updateReq.Email, updateReq.Username, updateReq.FirstName, updateReq.LastName)
+	h.logger.InfoContext(c, "Updating user", "user_id", id,
+		"email", updateReq.Email, "username", updateReq.Username, "first_name", updateReq.FirstName, "last_name", updateReq.LastName)
 
 	// Update fields if provided
 	if updateReq.Email != "" {
@@ -168,26 +176,46 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if updateReq.LastName != "" {
 		user.LastName = updateReq.LastName
 	}
+	if updateReq.Role != "" && updateReq.Role != user.Role {
+		if principal, ok := auth.FromContext(c); !ok || !principal.IsAdmin() {
+			h.logger.WarnContext(c, "Non-admin attempted to change role", "user_id", id)
+			respondError(c, http.StatusForbidden, "Forbidden", "Only administrators can change user roles")
+			return
+		}
+		// Outstanding tokens carry the old role, so make the user log in again
+		if err := h.revocations.RevokeUser(user.ID, h.cfg.Auth.AccessTokenTTL); err != nil {
+			h.logger.ErrorContext(c, "Failed to revoke tokens after role change", "user_id", id, "error", err)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to revoke user tokens")
+			return
+		}
+		h.logger.InfoContext(c, "Changing role", "user_id", id, "from", user.Role, "to", updateReq.Role)
+		user.Role = updateReq.Role
+	}
 	if updateReq.IsActive != nil {
+		// Deactivating a user cuts off every token they currently hold
+		if user.IsActive && !*updateReq.IsActive {
+			if err := h.revocations.RevokeUser(user.ID, h.cfg.Auth.AccessTokenTTL); err != nil {
+				h.logger.ErrorContext(c, "Failed to revoke tokens of deactivated user", "user_id", id, "error", err)
+				respondError(c, http.StatusInternalServerError, "Database error", "Failed to revoke user tokens")
+				return
+			}
+		}
 		user.IsActive = *updateReq.IsActive
 	}
 
-	if err := h.db.Save(&user).Error; err != nil {
-		log.Printf("User: Database error updating user ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to update user",
-		})
+	if err := h.db.WithContext(c.Request.Context()).Save(&user).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error updating user", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to update user")
 		return
 	}
 
-	log.Printf("User: Successfully updated user ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Updated user", "user_id", user.ID, "email", user.Email)
 	c.JSON(http.StatusOK, user)
 }
 
 // DeleteUser deletes a specific user
 // @Summary      Delete user
-// @Description  Delete a specific user by their ID
+// @Description  Delete a specific user by their ID. Non-admins can only delete their own record.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -201,45 +229,46 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		log.Printf("User: Invalid user ID format for delete: %s from %s", c.Param("id"), c.ClientIP())
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "Invalid ID",
-			Message: "User ID must be a valid number",
-		})
+		h.logger.InfoContext(c, "Invalid user ID format for delete", "id", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid ID", "User ID must be a valid number")
 		return
 	}
 
-	log.Printf("User: DeleteUser request for ID: %d from %s", id, c.ClientIP())
+	h.logger.DebugContext(c, "DeleteUser request", "user_id", id)
+
+	// Other users' records are reported as missing to avoid enumeration
+	if !canAccessUser(c, uint(id)) {
+		h.logger.InfoContext(c, "Caller may not access user, responding not found", "user_id", id)
+		respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
+		return
+	}
 
 	var user models.User
-	if err := h.db.First(&user, uint(id)).Error; err != nil {
+	if err := h.db.WithContext(c.Request.Context()).First(&user, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			log.Printf("User: User not found for delete with ID: %d", id)
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "User not found",
-				Message: "The requested user does not exist",
-			})
+			h.logger.InfoContext(c, "User not found for delete", "user_id", id)
+			respondError(c, http.StatusNotFound, "User not found", "The requested user does not exist")
 			return
 		}
-		log.Printf("User: Database error retrieving user for delete ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to retrieve user",
-		})
+		h.logger.ErrorContext(c, "Database error retrieving user for delete", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to retrieve user")
 		return
 	}
 
-	log.Printf("User: Deleting user ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Deleting user", "user_id", user.ID, "email", user.Email)
+
+	if err := h.revocations.RevokeUser(user.ID, h.cfg.Auth.AccessTokenTTL); err != nil {
+		h.logger.ErrorContext(c, "Failed to revoke tokens of deleted user", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to revoke user tokens")
+		return
+	}
 
-	if err := h.db.Delete(&user).Error; err != nil {
-		log.Printf("User: Database error deleting user ID: %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to delete user",
-		})
+	if err := h.db.WithContext(c.Request.Context()).Delete(&user).Error; err != nil {
+		h.logger.ErrorContext(c, "Database error deleting user", "user_id", id, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to delete user")
 		return
 	}
 
-	log.Printf("User: Successfully deleted user ID: %d, email: %s", user.ID, user.Email)
+	h.logger.InfoContext(c, "Deleted user", "user_id", user.ID, "email", user.Email)
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
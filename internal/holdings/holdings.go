@@ -0,0 +1,167 @@
+// Package holdings maintains per-user, per-asset balances.
+//
+// All functions take the *gorm.DB of an open database transaction so that
+// balance changes commit or roll back together with the transaction row.
+// Balances are exact decimals, so the arithmetic happens in Go rather than in
+// SQL (SQLite stores them as TEXT). Each write is guarded by the holding's
+// version column and retried if another request changed the holding first,
+// so concurrent requests cannot overdraw a holding.
+package holdings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientBalance is returned when a debit exceeds the available balance
+var ErrInsufficientBalance = errors.New("insufficient available balance")
+
+// maxAttempts bounds the optimistic-locking retries of a single balance change
+const maxAttempts = 5
+
+// errConflict signals that a holding changed between read and write
+var errConflict = errors.New("holding was modified concurrently")
+
+// Debits reports whether a transaction type removes the asset from the user's holdings
+func Debits(transactionType string) bool {
+	return transactionType == models.TransactionTypeSell || transactionType == models.TransactionTypeTransfer
+}
+
+// Reserve earmarks amount of the user's holding for a pending debit
+func Reserve(tx *gorm.DB, userID, assetID uint, amount money.Decimal) error {
+	return update(tx, userID, assetID, false, func(h *models.Holding) error {
+		if h.Quantity.Sub(h.Reserved).LessThan(amount) {
+			return ErrInsufficientBalance
+		}
+		h.Reserved = h.Reserved.Add(amount)
+		return nil
+	})
+}
+
+// Release returns a reservation made by Reserve
+func Release(tx *gorm.DB, userID, assetID uint, amount money.Decimal) error {
+	return update(tx, userID, assetID, false, func(h *models.Holding) error {
+		h.Reserved = h.Reserved.Sub(amount)
+		if h.Reserved.IsNegative() {
+			h.Reserved = money.Zero
+		}
+		return nil
+	})
+}
+
+// Credit adds amount to the user's holding, creating it if needed
+func Credit(tx *gorm.DB, userID, assetID uint, amount money.Decimal) error {
+	return update(tx, userID, assetID, true, func(h *models.Holding) error {
+		h.Quantity = h.Quantity.Add(amount)
+		return nil
+	})
+}
+
+// Debit removes amount from the user's holding and consumes the matching reservation
+func Debit(tx *gorm.DB, userID, assetID uint, amount money.Decimal) error {
+	return update(tx, userID, assetID, false, func(h *models.Holding) error {
+		if h.Quantity.LessThan(amount) {
+			return ErrInsufficientBalance
+		}
+		h.Quantity = h.Quantity.Sub(amount)
+		h.Reserved = h.Reserved.Sub(amount)
+		if h.Reserved.IsNegative() {
+			h.Reserved = money.Zero
+		}
+		return nil
+	})
+}
+
+// ReserveFor reserves the balance a pending transaction will debit. Buys reserve nothing.
+func ReserveFor(tx *gorm.DB, t *models.Transaction) error {
+	if !Debits(t.Type) {
+		return nil
+	}
+	return Reserve(tx, t.UserID, t.AssetID, t.Amount)
+}
+
+// ReleaseFor releases the reservation held by a pending transaction
+func ReleaseFor(tx *gorm.DB, t *models.Transaction) error {
+	if !Debits(t.Type) {
+		return nil
+	}
+	return Release(tx, t.UserID, t.AssetID, t.Amount)
+}
+
+// Settle applies a transaction that has just completed. The transaction's
+// reservation must already be held (see ReserveFor). Transfers are booked on
+// both sides: a debit for the sender and a credit for the recipient.
+func Settle(tx *gorm.DB, t *models.Transaction) error {
+	if !Debits(t.Type) {
+		return Credit(tx, t.UserID, t.AssetID, t.Amount)
+	}
+	if err := Debit(tx, t.UserID, t.AssetID, t.Amount); err != nil {
+		return err
+	}
+	if t.Type == models.TransactionTypeTransfer && t.RecipientID != nil {
+		return Credit(tx, *t.RecipientID, t.AssetID, t.Amount)
+	}
+	return nil
+}
+
+// update loads a holding, applies change to it and writes it back if its
+// version is unchanged, retrying on conflicts. A missing holding is created
+// when create is set and treated as an empty balance otherwise.
+func update(tx *gorm.DB, userID, assetID uint, create bool, change func(*models.Holding) error) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var holding models.Holding
+		err := tx.Where("user_id = ? AND asset_id = ?", userID, assetID).First(&holding).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !create {
+				return change(&models.Holding{})
+			}
+			// Another request may create the same holding; let the unique index decide
+			empty := models.Holding{UserID: userID, AssetID: assetID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&empty).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := change(&holding); err != nil {
+			return err
+		}
+
+		err = save(tx, &holding)
+		if errors.Is(err, errConflict) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("holding of user %d in asset %d: %w", userID, assetID, errConflict)
+}
+
+// save writes a holding's balances if nobody bumped its version since it was read
+func save(tx *gorm.DB, h *models.Holding) error {
+	result := tx.Model(&models.Holding{}).
+		Where("id = ? AND version = ?", h.ID, h.Version).
+		Updates(map[string]interface{}{
+			"quantity":   h.Quantity,
+			"reserved":   h.Reserved,
+			"version":    h.Version + 1,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errConflict
+	}
+	h.Version++
+	return nil
+}
@@ -0,0 +1,360 @@
+// Package listquery implements pagination, filtering and sorting for list endpoints.
+//
+// Each endpoint declares a Spec whitelisting the query parameters it accepts.
+// Parse validates a request against the Spec and Find runs the query, returning
+// one page of results and the pagination metadata for the response envelope.
+//
+// Pages can be requested by limit/offset or, for stable iteration over large
+// result sets, by the opaque cursor returned with each page. Cursors encode the
+// sort values of the last row (keyset pagination), so they stay correct when
+// rows are inserted while a client is paging.
+package listquery
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Page size limits
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// FilterKind selects how a filter parameter is compared with its column
+type FilterKind int
+
+const (
+	// FilterEqual matches rows whose column equals the parameter
+	FilterEqual FilterKind = iota
+	// FilterFrom matches rows whose column is at or after the parameter
+	FilterFrom
+	// FilterTo matches rows whose column is before the parameter
+	FilterTo
+)
+
+// Filter maps a query parameter to a column
+type Filter struct {
+	Column string
+	Kind   FilterKind
+}
+
+// Spec whitelists the filters and sort keys of a list endpoint
+type Spec struct {
+	Filters     map[string]Filter // query parameter -> filter
+	Sorts       map[string]string // sort key -> column
+	DefaultSort string            // e.g. "-created_at"
+}
+
+// ErrInvalidQuery wraps every error caused by bad query parameters
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query is a parsed list request
+type Query struct {
+	Limit  int
+	Offset int
+
+	schema     *schema.Schema
+	sort       []sortField
+	sortKey    string
+	conditions []condition
+	after      []interface{} // sort values of the last row of the previous page
+	url        *url.URL
+}
+
+type sortField struct {
+	field *schema.Field
+	desc  bool
+}
+
+type condition struct {
+	sql   string
+	value interface{}
+}
+
+// cursor is the decoded form of an opaque page cursor
+type cursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// Parse validates the pagination, filter and sort parameters of a request.
+// model is a pointer to the listed model, e.g. &models.Asset{}. Errors wrap
+// ErrInvalidQuery and are suitable for a 400 response.
+func Parse(c *gin.Context, db *gorm.DB, model interface{}, spec Spec) (*Query, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	q := &Query{Limit: DefaultLimit, schema: stmt.Schema, url: c.Request.URL}
+	params := c.Request.URL.Query()
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+		}
+		q.Limit = limit
+	}
+	if v := params.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("%w: offset must be a non-negative number", ErrInvalidQuery)
+		}
+		q.Offset = offset
+	}
+
+	if err := q.parseSort(params.Get("sort"), spec); err != nil {
+		return nil, err
+	}
+
+	for param, filter := range spec.Filters {
+		raw := params.Get(param)
+		if raw == "" {
+			continue
+		}
+		field := q.schema.LookUpField(filter.Column)
+		if field == nil {
+			return nil, fmt.Errorf("listquery: unknown filter column %q", filter.Column)
+		}
+		value, err := parseValue(field, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidQuery, param, err)
+		}
+		op := "="
+		switch filter.Kind {
+		case FilterFrom:
+			op = ">="
+		case FilterTo:
+			op = "<"
+		}
+		q.conditions = append(q.conditions, condition{sql: q.column(field) + " " + op + " ?", value: value})
+	}
+
+	if v := params.Get("cursor"); v != "" {
+		if params.Get("offset") != "" {
+			return nil, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidQuery)
+		}
+		if err := q.decodeCursor(v); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// parseSort parses a comma-separated list of sort keys, each optionally
+// prefixed with "-" for descending order. The primary key is always appended
+// as a tie-breaker so that the order, and therefore cursors, are stable.
+func (q *Query) parseSort(raw string, spec Spec) error {
+	if raw == "" {
+		raw = spec.DefaultSort
+	}
+	var keys []string
+	hasPrimaryKey := false
+	for _, key := range strings.Split(raw, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		desc := strings.HasPrefix(key, "-")
+		column, ok := spec.Sorts[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, strings.TrimPrefix(key, "-"))
+		}
+		field := q.schema.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("listquery: unknown sort column %q", column)
+		}
+		if field == q.schema.PrioritizedPrimaryField {
+			hasPrimaryKey = true
+		}
+		q.sort = append(q.sort, sortField{field: field, desc: desc})
+		keys = append(keys, key)
+	}
+	if !hasPrimaryKey {
+		q.sort = append(q.sort, sortField{field: q.schema.PrioritizedPrimaryField})
+		keys = append(keys, q.schema.PrioritizedPrimaryField.DBName)
+	}
+	q.sortKey = strings.Join(keys, ",")
+	return nil
+}
+
+func (q *Query) decodeCursor(raw string) error {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return invalid
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil || len(cur.Values) != len(q.sort) {
+		return invalid
+	}
+	if cur.Sort != q.sortKey {
+		return fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidQuery)
+	}
+	for i, sf := range q.sort {
+		value := reflect.New(sf.field.FieldType)
+		if err := json.Unmarshal(cur.Values[i], value.Interface()); err != nil {
+			return invalid
+		}
+		q.after = append(q.after, value.Elem().Interface())
+	}
+	return nil
+}
+
+// Find loads one page of results into dest, a pointer to a slice of the model.
+// db may already carry scopes such as ownership checks; preloads are applied
+// to the page query only.
+func (q *Query) Find(db *gorm.DB, dest interface{}, preloads ...string) (models.Pagination, error) {
+	base := q.filtered(db)
+
+	page := models.Pagination{Limit: q.Limit, Offset: q.Offset}
+	if err := base.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	query := base.Session(&gorm.Session{})
+	if q.after != nil {
+		sql, values := q.keysetCondition()
+		query = query.Where(sql, values...)
+		page.Offset = 0
+	} else if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+	query = q.ordered(query)
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	// Fetch one extra row to learn whether there is a next page
+	if err := query.Limit(q.Limit + 1).Find(dest).Error; err != nil {
+		return page, err
+	}
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() <= q.Limit {
+		return page, nil
+	}
+	rows.Set(rows.Slice(0, q.Limit))
+
+	next, err := q.encodeCursor(db, rows.Index(q.Limit-1))
+	if err != nil {
+		return page, err
+	}
+	page.NextCursor = next
+	page.Next = q.nextLink(next)
+	return page, nil
+}
+
+// Rows runs the query without pagination, for exports: limit, offset and
+// cursor are ignored. db may carry scopes, joins and a Select of the columns
+// to return; the caller scans each row and must close the rows.
+func (q *Query) Rows(db *gorm.DB) (*sql.Rows, error) {
+	return q.ordered(q.filtered(db)).Rows()
+}
+
+// filtered applies the filter conditions to the model
+func (q *Query) filtered(db *gorm.DB) *gorm.DB {
+	query := db.Model(reflect.New(q.schema.ModelType).Interface())
+	for _, cond := range q.conditions {
+		query = query.Where(cond.sql, cond.value)
+	}
+	return query
+}
+
+// ordered applies the sort order
+func (q *Query) ordered(db *gorm.DB) *gorm.DB {
+	for _, sf := range q.sort {
+		order := q.column(sf.field)
+		if sf.desc {
+			order += " DESC"
+		}
+		db = db.Order(order)
+	}
+	return db
+}
+
+// keysetCondition selects the rows that sort after q.after:
+// (a > x) OR (a = x AND b > y) OR ...
+func (q *Query) keysetCondition() (string, []interface{}) {
+	var clauses []string
+	var values []interface{}
+	for i, sf := range q.sort {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, q.column(q.sort[j].field)+" = ?")
+			values = append(values, q.after[j])
+		}
+		op := ">"
+		if sf.desc {
+			op = "<"
+		}
+		parts = append(parts, q.column(sf.field)+" "+op+" ?")
+		values = append(values, q.after[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", values
+}
+
+func (q *Query) encodeCursor(db *gorm.DB, row reflect.Value) (string, error) {
+	cur := cursor{Sort: q.sortKey}
+	for _, sf := range q.sort {
+		value, _ := sf.field.ValueOf(db.Statement.Context, reflect.Indirect(row))
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cur.Values = append(cur.Values, raw)
+	}
+	data, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// nextLink is the request URL with the cursor of the next page
+func (q *Query) nextLink(next string) string {
+	params := q.url.Query()
+	params.Del("offset")
+	params.Set("cursor", next)
+	return q.url.Path + "?" + params.Encode()
+}
+
+func (q *Query) column(field *schema.Field) string {
+	return q.schema.Table + "." + field.DBName
+}
+
+// parseValue converts a query parameter to the type of a model field
+func parseValue(field *schema.Field, raw string) (interface{}, error) {
+	switch field.FieldType.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, 64)
+	case reflect.String:
+		return raw, nil
+	}
+	if field.FieldType == reflect.TypeOf(time.Time{}) {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", raw)
+	}
+	return nil, fmt.Errorf("unsupported filter type %s", field.FieldType)
+}
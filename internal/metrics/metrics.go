@@ -0,0 +1,90 @@
+// Package metrics defines the application's Prometheus metrics.
+//
+// NewRegistry creates a registry holding the collectors together with the Go
+// runtime and process metrics, and Handler serves a registry. HTTP requests
+// are recorded by middleware.Metrics, database queries by the GORM Plugin and
+// connection pool stats by RegisterDBStats.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Login results
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+)
+
+var (
+	// HTTPRequests counts handled requests by method, route template and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latencies by method, route template and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latencies by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueries counts GORM statements by operation, table and outcome
+	DBQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_queries_total",
+		Help: "Database statements by operation, table and status (ok or error).",
+	}, []string{"operation", "table", "status"})
+
+	// DBQueryDuration observes GORM statement latencies by operation and table
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database statement latencies by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// TransactionsCreated counts created transactions by type and initial status
+	TransactionsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "transactions_created_total",
+		Help: "Transactions created by type and status.",
+	}, []string{"type", "status"})
+
+	// Logins counts login attempts by result (succeeded or failed)
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "logins_total",
+		Help: "Login attempts by result.",
+	}, []string{"result"})
+)
+
+// NewRegistry creates a registry with the application's collectors and the Go
+// runtime and process metrics
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueries,
+		DBQueryDuration,
+		TransactionsCreated,
+		Logins,
+	)
+	return registry
+}
+
+// RegisterDBStats exports the connection pool stats of db to registry,
+// labelled with name
+func RegisterDBStats(registry prometheus.Registerer, db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics of gatherer in the Prometheus text format
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-api-test1/internal/auth"
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyHeader is the request header that makes a POST request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength matches the size of the key column
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodyBytes bounds the bodies read into memory to fingerprint
+// them; it matches the largest body an idempotent route accepts, an import
+const maxIdempotentBodyBytes = 5 << 20
+
+// idempotencyPruneInterval is how often PruneIdempotencyRecords runs
+const idempotencyPruneInterval = time.Hour
+
+// idempotencyRecorder copies the response body so that it can be stored
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key. Keys are scoped to the authenticated user, so the
+// middleware must run after AuthMiddleware; keys of anonymous requests, such as
+// registrations, are scoped to the client's address instead, so that callers
+// cannot replay each other's responses. Reusing a key with a different request
+// is rejected with 422, and a retry that arrives while the first request is
+// still running gets 409. Server errors and panics are not stored, so those
+// requests can be retried with the same key; expired keys can be reused too.
+func Idempotency(db *gorm.DB, cfg *config.Config, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			logger.InfoContext(c, "Idempotency key too long", "path", c.Request.URL.Path)
+			respondError(c, http.StatusBadRequest, "Invalid request", "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+		var userID uint
+		if principal, ok := auth.FromContext(c); ok {
+			userID = principal.UserID
+		} else {
+			key = anonymousIdempotencyKey(c.ClientIP(), key)
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			message := "Failed to read request body"
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				message = fmt.Sprintf("Requests with an Idempotency-Key may be at most %d bytes", tooLarge.Limit)
+			}
+			logger.WarnContext(c, "Failed to read request body", "error", err)
+			respondError(c, http.StatusBadRequest, "Invalid request", message)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		db := db.WithContext(c.Request.Context())
+
+		record := models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: requestFingerprint(c, body),
+			ExpiresAt:   now.Add(cfg.IdempotencyTTL),
+		}
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			logger.ErrorContext(c, "Database error storing idempotency key", "user_id", userID, "error", result.Error)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to store idempotency key")
+			c.Abort()
+			return
+		}
+
+		if result.RowsAffected == 0 && !reuseIdempotencyKey(c, db, logger, &record, now) {
+			return
+		}
+
+		// The outcome is recorded even if the client has gone away meanwhile
+		db = db.WithContext(context.WithoutCancel(c.Request.Context()))
+		release := func() {
+			// Let the client retry with the same key
+			if err := db.Delete(&record).Error; err != nil {
+				logger.ErrorContext(c, "Failed to release idempotency key", "user_id", userID, "error", err)
+			}
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				release()
+				panic(r)
+			}
+		}()
+
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+		err = db.Model(&record).Updates(map[string]interface{}{
+			"status_code":   status,
+			"content_type":  recorder.Header().Get("Content-Type"),
+			"response_body": recorder.body.Bytes(),
+		}).Error
+		if err != nil {
+			logger.ErrorContext(c, "Failed to store idempotent response", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// reuseIdempotencyKey handles a request whose key has already been used. An
+// expired key is claimed for the request, which then runs as if the key were
+// new, and true is returned; otherwise the request is answered and aborted.
+func reuseIdempotencyKey(c *gin.Context, db *gorm.DB, logger *slog.Logger, record *models.IdempotencyRecord, now time.Time) bool {
+	userID := record.UserID
+	var existing models.IdempotencyRecord
+	if err := db.Where("user_id = ? AND key = ?", userID, record.Key).First(&existing).Error; err != nil {
+		logger.ErrorContext(c, "Database error loading idempotency key", "user_id", userID, "error", err)
+		respondError(c, http.StatusInternalServerError, "Database error", "Failed to load idempotency key")
+		c.Abort()
+		return false
+	}
+
+	if !existing.ExpiresAt.After(now) {
+		// Only one of several concurrent requests takes the key over
+		result := db.Model(&existing).Where("expires_at <= ?", now).Updates(map[string]interface{}{
+			"fingerprint":   record.Fingerprint,
+			"status_code":   0,
+			"content_type":  "",
+			"response_body": nil,
+			"expires_at":    record.ExpiresAt,
+			"created_at":    now,
+		})
+		if result.Error != nil {
+			logger.ErrorContext(c, "Database error storing idempotency key", "user_id", userID, "error", result.Error)
+			respondError(c, http.StatusInternalServerError, "Database error", "Failed to store idempotency key")
+			c.Abort()
+			return false
+		}
+		if result.RowsAffected == 1 {
+			record.ID = existing.ID
+			return true
+		}
+		logger.InfoContext(c, "Retry of in-progress idempotent request", "user_id", userID)
+		respondError(c, http.StatusConflict, "Request in progress", "A request with this Idempotency-Key is still being processed")
+		c.Abort()
+		return false
+	}
+
+	switch {
+	case existing.Fingerprint != record.Fingerprint:
+		logger.InfoContext(c, "Idempotency key reused with a different request", "user_id", userID)
+		respondError(c, http.StatusUnprocessableEntity, "Idempotency key reused", "This Idempotency-Key was already used with a different request")
+	case existing.StatusCode == 0:
+		logger.InfoContext(c, "Retry of in-progress idempotent request", "user_id", userID)
+		respondError(c, http.StatusConflict, "Request in progress", "A request with this Idempotency-Key is still being processed")
+	default:
+		logger.InfoContext(c, "Replaying stored idempotent response", "user_id", userID, "status", existing.StatusCode)
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+	}
+	c.Abort()
+	return false
+}
+
+// anonymousIdempotencyKey scopes the key of an unauthenticated request to the
+// client's address. The two are hashed, so that addresses are not stored and
+// the result fits the key column.
+func anonymousIdempotencyKey(clientIP, key string) string {
+	h := sha256.Sum256([]byte(clientIP + "\n" + key))
+	return "anonymous:" + hex.EncodeToString(h[:])
+}
+
+// PruneIdempotencyRecords deletes expired idempotency keys every hour until
+// ctx is cancelled. Expired keys are ignored when they are looked up, so this
+// only keeps the table from growing.
+func PruneIdempotencyRecords(ctx context.Context, db *gorm.DB, logger *slog.Logger) {
+	ticker := time.NewTicker(idempotencyPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := db.WithContext(ctx).Where("expires_at <= ?", time.Now().UTC()).Delete(&models.IdempotencyRecord{})
+		if result.Error != nil {
+			logger.ErrorContext(ctx, "Failed to prune expired idempotency keys", "error", result.Error)
+			continue
+		}
+		logger.DebugContext(ctx, "Pruned expired idempotency keys", "deleted", result.RowsAffected)
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body
+func requestFingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -1,44 +1,122 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"go-api-test1/internal/auth"
 	"go-api-test1/internal/config"
+	"go-api-test1/internal/logging"
+	"go-api-test1/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// LoggerMiddleware logs HTTP requests with timing and status information
-func LoggerMiddleware() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("[%s] %s %s %d %s %s %s\n",
-			param.TimeStamp.Format(time.RFC3339),
-			param.Method,
-			param.Path,
-			param.StatusCode,
-			param.Latency,
-			param.ClientIP,
-			param.ErrorMessage,
-		)
-	})
+// RequestIDHeader carries the ID of a request in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestID adopts the client's X-Request-ID, or generates one, and attaches it
+// to the request's context and to the response. Log records written with the
+// request's context and error responses carry it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(logging.RequestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Logger logs every HTTP request with its status and timing. Errors attached
+// to the context with c.Error, such as the reasons middleware rejected the
+// request, are logged with it. It must run after RequestID.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", strings.Join(c.Errors.Errors(), "; ")))
+		}
+		logger.LogAttrs(c, level, "HTTP request", attrs...)
+	}
 }
 
-// CORS middleware for handling cross-origin requests
-func CORS() gin.HandlerFunc {
+// CORS middleware for handling cross-origin requests from the configured
+// origins. Requests from other origins get no CORS headers, so browsers block
+// them.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowed[origin] = true
+	}
 	return func(c *gin.Context) {
-		log.Printf("CORS: Processing %s request from %s to %s", c.Request.Method, c.ClientIP(), c.Request.URL.Path)
-		
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		origin := "*"
+		if !allowed[origin] {
+			origin = c.GetHeader("Origin")
+			c.Header("Vary", "Origin")
+		}
+		if allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key, X-Request-ID")
+			c.Header("Access-Control-Expose-Headers", "X-Request-ID")
+		}
 
 		if c.Request.Method == "OPTIONS" {
-			log.Printf("CORS: Handling OPTIONS preflight request from %s", c.ClientIP())
 			c.AbortWithStatus(204)
 			return
 		}
@@ -47,23 +125,23 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens signed with the configured secret and
+// rejects tokens that have been revoked
+func AuthMiddleware(cfg *config.Config, revocations *auth.RevocationStore) gin.HandlerFunc {
+	secret := []byte(cfg.Auth.JWTSecret)
 	return func(c *gin.Context) {
-		log.Printf("Auth: Validating token for %s request to %s from %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
-		
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Printf("Auth: Missing authorization header for %s from %s", c.Request.URL.Path, c.ClientIP())
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Error(errors.New("missing authorization header"))
+			respondError(c, http.StatusUnauthorized, "Authorization header required", "Send a bearer token in the Authorization header")
 			c.Abort()
 			return
 		}
 
 		// Check if the header starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			log.Printf("Auth: Invalid authorization header format for %s from %s", c.Request.URL.Path, c.ClientIP())
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+			c.Error(errors.New("invalid authorization header format"))
+			respondError(c, http.StatusUnauthorized, "Invalid authorization header format", "The Authorization header must be \"Bearer <token>\"")
 			c.Abort()
 			return
 		}
@@ -72,40 +150,61 @@ func AuthMiddleware() gin.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		claims := &auth.Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			// Validate the signing method
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Printf("Auth: Invalid signing method for token from %s", c.ClientIP())
 				return nil, jwt.ErrSignatureInvalid
 			}
-			return []byte(config.Load().JWTSecret), nil
+			return secret, nil
 		})
 
 		if err != nil || !token.Valid {
-			log.Printf("Auth: Invalid or expired token for %s from %s: %v", c.Request.URL.Path, c.ClientIP(), err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Error(fmt.Errorf("invalid or expired token: %v", err))
+			respondError(c, http.StatusUnauthorized, "Invalid token", "The token is invalid or has expired")
 			c.Abort()
 			return
 		}
 
-		// Extract user ID from claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if userID, exists := claims["user_id"]; exists {
-				log.Printf("Auth: Token validated successfully for user %v accessing %s", userID, c.Request.URL.Path)
-				c.Set("user_id", userID)
-			} else {
-				log.Printf("Auth: Missing user_id in token claims for %s from %s", c.Request.URL.Path, c.ClientIP())
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-				c.Abort()
-				return
-			}
-		} else {
-			log.Printf("Auth: Invalid token claims format for %s from %s", c.Request.URL.Path, c.ClientIP())
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		// Tokens without an ID cannot be revoked, so they are not accepted
+		if claims.UserID == 0 || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+			c.Error(errors.New("missing user_id/jti/iat/exp in token claims"))
+			respondError(c, http.StatusUnauthorized, "Invalid token claims", "The token is missing required claims")
 			c.Abort()
 			return
 		}
 
+		principal := auth.NewPrincipal(claims)
+		if revocations.IsRevoked(principal.TokenID, principal.UserID, principal.IssuedAt) {
+			c.Error(fmt.Errorf("revoked token %s presented for user %d", principal.TokenID, principal.UserID))
+			respondError(c, http.StatusUnauthorized, "Token has been revoked", "Log in again to get a new token")
+			c.Abort()
+			return
+		}
+
+		auth.SetPrincipal(c, principal)
+
 		c.Next()
 	}
 }
+
+// RequirePermission allows the request through only if the authenticated user's
+// role (and token scopes, if any) grant perm. It must run after AuthMiddleware.
+func RequirePermission(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.FromContext(c)
+		if !ok || !principal.Can(perm) {
+			c.Error(fmt.Errorf("caller lacks permission %s", perm))
+			respondError(c, http.StatusForbidden, "Insufficient permissions", "Your role does not allow this request")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// respondError writes an ErrorResponse quoting the request and trace IDs; the
+// caller still has to abort the chain
+func respondError(c *gin.Context, status int, err, message string) {
+	c.JSON(status, models.NewErrorResponse(c, err, message))
+}
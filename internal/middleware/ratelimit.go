@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-api-test1/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits each client, identified by its IP address, to the
+// configured rate with bursts of up to cfg.Burst requests. Requests over the
+// limit get 429 with a Retry-After header. A rate of 0 disables the limit.
+func RateLimit(cfg config.RateLimitConfig) gin.HandlerFunc {
+	if cfg.RequestsPerSecond <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	limiter := &rateLimiter{
+		rate:    cfg.RequestsPerSecond,
+		burst:   float64(cfg.Burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+	return func(c *gin.Context) {
+		client := c.ClientIP()
+		if wait := limiter.take(client, time.Now()); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.Error(fmt.Errorf("rate limit exceeded by %s", client))
+			respondError(c, http.StatusTooManyRequests, "Too many requests", "Retry after the number of seconds in the Retry-After header")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenBucket holds a client's remaining requests as of updated
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket per client
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// take spends one of key's tokens, or returns how long until one is available
+func (l *rateLimiter) take(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep forgets the buckets that have refilled completely, so that the map
+// does not grow with every client ever seen. It runs at most once a minute.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
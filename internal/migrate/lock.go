@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// advisoryLockKey identifies the migration lock among PostgreSQL advisory locks
+const advisoryLockKey int64 = 0x676f6170695f6d67 // "goapi_mg"
+
+// staleLockAge is when a SQLite lock row left behind by a crashed run is ignored
+const staleLockAge = 10 * time.Minute
+
+// lockPollInterval is how often a waiting instance retries the lock
+const lockPollInterval = 250 * time.Millisecond
+
+// withLock runs fn on a dedicated connection while holding the migration lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == DialectSQLite {
+		// The lock row needs its table before it can be taken
+		if err := m.ensureTables(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		acquired, err := m.tryLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+	defer m.unlock(conn)
+
+	if err := m.ensureTables(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// tryLock takes the migration lock without waiting
+func (m *Migrator) tryLock(ctx context.Context, conn *sql.Conn) (bool, error) {
+	if m.dialect == DialectPostgres {
+		// Session-level advisory locks are released automatically if the connection drops
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&acquired)
+		return acquired, err
+	}
+
+	now := time.Now().UTC()
+	if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE locked_at < ?", now.Add(-staleLockAge)); err != nil {
+		return false, err
+	}
+	result, err := conn.ExecContext(ctx, "INSERT OR IGNORE INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", now)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// unlock releases the migration lock, even if the caller's context was cancelled
+func (m *Migrator) unlock(conn *sql.Conn) {
+	var err error
+	if m.dialect == DialectPostgres {
+		_, err = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+	} else {
+		_, err = conn.ExecContext(context.Background(), "DELETE FROM schema_migrations_lock WHERE id = 1")
+	}
+	if err != nil {
+		// Not fatal: PostgreSQL drops the lock with the connection and SQLite treats it as stale later
+		slog.Error("Failed to release migration lock", "component", "migrate", "error", err)
+	}
+}
@@ -0,0 +1,400 @@
+// Package migrate applies versioned SQL migrations.
+//
+// Migrations are read from a file system (normally the embedded
+// go-api-test1/migrations package) with one directory per dialect, each holding
+// files named <version>_<name>.up.sql and <version>_<name>.down.sql. Applied
+// versions are recorded in the schema_migrations table together with a
+// checksum of their up script, so that editing a migration after it has been
+// applied is detected instead of silently diverging.
+//
+// Every run holds a lock (a PostgreSQL advisory lock, or a lock row on SQLite)
+// so that several instances starting at the same time do not race, and each
+// migration runs in its own database transaction together with its
+// schema_migrations bookkeeping. On SQLite, foreign keys are not enforced while
+// a migration runs, so that it can rebuild tables, and are checked before it
+// is committed instead.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported dialects, named like the GORM dialectors
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// ErrChecksumMismatch is returned when an applied migration's file has changed
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// ErrLockTimeout is returned when another instance holds the migration lock for too long
+var ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up
+}
+
+// Status describes a migration known from the files, the database or both
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is set when the file changed after the migration was applied
+	Modified bool
+	// Missing is set when the migration was applied but its file no longer exists
+	Missing bool
+}
+
+// Migrator applies the migrations of one dialect to a database
+type Migrator struct {
+	db          *sql.DB
+	dialect     string
+	migrations  []Migration
+	LockTimeout time.Duration
+}
+
+// New loads the migrations for dialect from fsys
+func New(db *sql.DB, dialect string, fsys fs.FS) (*Migrator, error) {
+	if dialect != DialectPostgres && dialect != DialectSQLite {
+		return nil, fmt.Errorf("migrate: unsupported dialect %q", dialect)
+	}
+	migrations, err := load(fsys, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, LockTimeout: 2 * time.Minute}, nil
+}
+
+// Migrations returns the known migrations in version order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// load reads and pairs the up and down scripts of a dialect
+func load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: reading %s migrations: %w", dialect, err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionPart, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migrate: %s: expected <version>_<name>.%s.sql", name, direction)
+		}
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migrate: %s: invalid version %q", name, versionPart)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dialect, name))
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: label}
+			byVersion[version] = mig
+		} else if mig.Name != label {
+			return nil, fmt.Errorf("migrate: version %d is used by both %q and %q", version, mig.Name, label)
+		}
+		if direction == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up applies up to n pending migrations, or all of them if n <= 0, and
+// returns the ones it applied
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if n > 0 && len(done) == n {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the n most recently applied migrations (at least one) and
+// returns the ones it reverted
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n <= 0 {
+		n = 1
+	}
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: version %d (%s) has no down script", mig.Version, mig.Name)
+			}
+			if err := m.run(ctx, conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo reverts and re-applies the most recently applied migration
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) == 0 {
+		return nil, err
+	}
+	if _, err := m.Up(ctx, 1); err != nil {
+		return nil, err
+	}
+	return &reverted[0], nil
+}
+
+// Status lists every migration with whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := m.ensureTables(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.appliedAt
+		statuses = append(statuses, Status{Version: row.version, Name: row.name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// verify refuses to migrate a database whose applied migrations were edited
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, mig := range m.migrations {
+		if row, ok := applied[mig.Version]; ok && row.checksum != mig.Checksum {
+			return fmt.Errorf("%w: version %d (%s) was modified after it was applied", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// run executes one migration script and records it, atomically
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, mig Migration, script string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	foreignKeys := false
+	if m.dialect == DialectSQLite {
+		// SQLite ignores this pragma inside a transaction, so it is switched
+		// off on the connection for the duration of the migration
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			return err
+		}
+		if foreignKeys {
+			if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+				return err
+			}
+			defer conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA foreign_keys = ON")
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migrate: %s %d (%s): %w", direction, mig.Version, mig.Name, err)
+	}
+	if foreignKeys {
+		if err := checkForeignKeys(ctx, tx); err != nil {
+			return fmt.Errorf("migrate: %s %d (%s): %w", direction, mig.Version, mig.Name, err)
+		}
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+			mig.Version, mig.Name, mig.Checksum, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version = ?"), mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys fails if any row of a SQLite database violates a foreign key
+func checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fk int
+		if err := rows.Scan(&table, &rowID, &parent, &fk); err != nil {
+			return err
+		}
+		return fmt.Errorf("row %d of %s references a missing row of %s", rowID.Int64, table, parent)
+	}
+	return rows.Err()
+}
+
+// applied reads schema_migrations
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[row.version] = row
+	}
+	return applied, rows.Err()
+}
+
+// ensureTables creates the bookkeeping tables
+func (m *Migrator) ensureTables(ctx context.Context, conn *sql.Conn) error {
+	statements := []string{`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`}
+	if m.dialect == DialectSQLite {
+		statements = append(statements, `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id integer PRIMARY KEY CHECK (id = 1),
+			locked_at timestamp NOT NULL
+		)`)
+	}
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bind rewrites ? placeholders for the dialect
+func (m *Migrator) bind(query string) string {
+	if m.dialect != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,11 +1,23 @@
 package models
 
 import (
+	"context"
 	"time"
 
+	"go-api-test1/internal/logging"
+	"go-api-test1/internal/money"
+
 	"gorm.io/gorm"
 )
 
+// User roles
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleTrader   = "trader"
+	RoleReadOnly = "read-only"
+)
+
 // User represents a user in the system
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey" example:"1"`
@@ -14,6 +26,7 @@ type User struct {
 	Password  string         `json:"-" gorm:"not null"` // Hidden from JSON
 	FirstName string         `json:"first_name" example:"John"`
 	LastName  string         `json:"last_name" example:"Doe"`
+	Role      string         `json:"role" gorm:"not null;default:'trader'" example:"trader"` // admin, operator, trader, read-only
 	IsActive  bool           `json:"is_active" gorm:"default:true" example:"true"`
 	CreatedAt time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
@@ -22,38 +35,292 @@ type User struct {
 
 // Asset represents an asset in the system
 type Asset struct {
-	ID          uint           `json:"id" gorm:"primaryKey" example:"1"`
-	Name        string         `json:"name" gorm:"not null" example:"Bitcoin"`
-	Symbol      string         `json:"symbol" gorm:"uniqueIndex;not null" example:"BTC"`
-	Type        string         `json:"type" gorm:"not null" example:"cryptocurrency"`
-	Description string         `json:"description" example:"Digital currency"`
-	Price       float64        `json:"price" gorm:"not null" example:"50000.00"`
-	IsActive    bool           `json:"is_active" gorm:"default:true" example:"true"`
-	CreatedAt   time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt   time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey" example:"1"`
+	Name           string         `json:"name" gorm:"not null" example:"Bitcoin"`
+	Symbol         string         `json:"symbol" gorm:"uniqueIndex;not null" example:"BTC"`
+	Type           string         `json:"type" gorm:"not null" example:"cryptocurrency"`
+	Description    string         `json:"description" example:"Digital currency"`
+	Price          money.Decimal  `json:"price" gorm:"not null" swaggertype:"string" example:"50000.00"`
+	QuantityScale  *int32         `json:"quantity_scale" gorm:"not null;default:8" example:"8"`      // Decimal places of amounts
+	PriceScale     *int32         `json:"price_scale" gorm:"not null;default:2" example:"2"`         // Decimal places of prices
+	PriceUpdatedAt *time.Time     `json:"price_updated_at" example:"2023-01-01T00:00:00Z"`           // When Price was last set or confirmed
+	PriceStale     bool           `json:"price_stale" gorm:"not null;default:false" example:"false"` // Set by the price feed once Price is too old
+	IsActive       bool           `json:"is_active" gorm:"default:true" example:"true"`
+	CreatedAt      time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt      time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Default number of decimal places of an asset's amounts and prices
+const (
+	DefaultQuantityScale int32 = 8
+	DefaultPriceScale    int32 = 2
+)
+
+// AmountScale returns the number of decimal places allowed in amounts of the asset
+func (a Asset) AmountScale() int32 {
+	if a.QuantityScale == nil {
+		return DefaultQuantityScale
+	}
+	return *a.QuantityScale
+}
+
+// PricingScale returns the number of decimal places allowed in prices of the asset
+func (a Asset) PricingScale() int32 {
+	if a.PriceScale == nil {
+		return DefaultPriceScale
+	}
+	return *a.PriceScale
+}
+
+// Price sources
+const (
+	PriceSourceInitial = "initial" // Price the asset was created with
+	PriceSourceManual  = "manual"  // Set through the asset API
+	PriceSourceFeed    = "feed"    // Ingested from the price feed
+	PriceSourceImport  = "import"  // Set by a bulk asset import
+)
+
+// AssetPrice is an entry of an asset's append-only price history
+type AssetPrice struct {
+	ID         uint          `json:"id" gorm:"primaryKey" example:"1"`
+	AssetID    uint          `json:"asset_id" gorm:"not null;index:idx_asset_prices_asset_recorded" example:"1"`
+	Price      money.Decimal `json:"price" gorm:"not null" swaggertype:"string" example:"50000.00"`
+	Source     string        `json:"source" gorm:"not null" example:"manual"` // initial, manual, feed, import
+	RecordedAt time.Time     `json:"recorded_at" gorm:"not null;index:idx_asset_prices_asset_recorded" example:"2023-01-01T00:00:00Z"`
+	CreatedAt  time.Time     `json:"-"`
+}
+
+// Candle aggregates the prices and completed trades of an asset over one interval
+type Candle struct {
+	Time   time.Time     `json:"time" example:"2023-01-01T00:00:00Z"` // Start of the interval
+	Open   money.Decimal `json:"open" swaggertype:"string" example:"50000.00"`
+	High   money.Decimal `json:"high" swaggertype:"string" example:"51000.00"`
+	Low    money.Decimal `json:"low" swaggertype:"string" example:"49500.00"`
+	Close  money.Decimal `json:"close" swaggertype:"string" example:"50500.00"`
+	Volume money.Decimal `json:"volume" swaggertype:"string" example:"12.5"` // Traded amount
+	Trades int           `json:"trades" example:"7"`
+}
+
+// CandlesResponse is the response of the candles endpoint
+type CandlesResponse struct {
+	AssetID  uint      `json:"asset_id" example:"1"`
+	Interval string    `json:"interval" example:"1h"`
+	From     time.Time `json:"from" example:"2023-01-01T00:00:00Z"`
+	To       time.Time `json:"to" example:"2023-01-02T00:00:00Z"`
+	Candles  []Candle  `json:"candles"`
 }
 
+// Transaction types
+const (
+	TransactionTypeBuy      = "buy"
+	TransactionTypeSell     = "sell"
+	TransactionTypeTransfer = "transfer"
+)
+
+// Transaction statuses
+const (
+	TransactionStatusPending   = "pending"
+	TransactionStatusCompleted = "completed"
+	TransactionStatusFailed    = "failed"
+	TransactionStatusCancelled = "cancelled"
+)
+
+// Transfer directions, relative to the user viewing the transaction
+const (
+	TransferDirectionIncoming = "incoming"
+	TransferDirectionOutgoing = "outgoing"
+)
+
 // Transaction represents a transaction between users and assets
 type Transaction struct {
 	ID          uint           `json:"id" gorm:"primaryKey" example:"1"`
 	UserID      uint           `json:"user_id" gorm:"not null" example:"1"`
 	AssetID     uint           `json:"asset_id" gorm:"not null" example:"1"`
 	Type        string         `json:"type" gorm:"not null" example:"buy"` // buy, sell, transfer
-	Amount      float64        `json:"amount" gorm:"not null" example:"0.5"`
-	Price       float64        `json:"price" gorm:"not null" example:"50000.00"`
-	TotalValue  float64        `json:"total_value" gorm:"not null" example:"25000.00"`
+	Amount      money.Decimal  `json:"amount" gorm:"not null" swaggertype:"string" example:"0.5"`
+	Price       money.Decimal  `json:"price" gorm:"not null" swaggertype:"string" example:"50000.00"`
+	TotalValue  money.Decimal  `json:"total_value" gorm:"not null" swaggertype:"string" example:"25000.00"`
 	Status      string         `json:"status" gorm:"default:'pending'" example:"completed"` // pending, completed, failed, cancelled
 	Description string         `json:"description" example:"Buying Bitcoin"`
+	RecipientID *uint          `json:"recipient_id,omitempty" gorm:"index" example:"2"` // Transfers only
+	Direction   string         `json:"direction,omitempty" gorm:"-" example:"outgoing"` // Transfers only: incoming, outgoing
+	Version     uint           `json:"-" gorm:"not null;default:0"`                      // Bumped by every change
 	CreatedAt   time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
 	UpdatedAt   time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
-	
+
 	// Relationships
 	User  User  `json:"user" gorm:"foreignKey:UserID"`
 	Asset Asset `json:"asset" gorm:"foreignKey:AssetID"`
 }
 
+// TransactionStatusChange records a status change of a transaction: who made it,
+// when, and from which status to which. Creation is recorded with an empty FromStatus.
+type TransactionStatusChange struct {
+	ID            uint      `json:"id" gorm:"primaryKey" example:"1"`
+	TransactionID uint      `json:"transaction_id" gorm:"not null;index" example:"1"`
+	FromStatus    string    `json:"from_status" example:"pending"`
+	ToStatus      string    `json:"to_status" gorm:"not null" example:"completed"`
+	ChangedByID   uint      `json:"changed_by_id" gorm:"not null" example:"1"`
+	Reason        string    `json:"reason" example:"Settled by exchange"`
+	CreatedAt     time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// TransactionExport is a flat row of a transaction export
+type TransactionExport struct {
+	ID          uint          `json:"id" example:"1"`
+	CreatedAt   time.Time     `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt   time.Time     `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	Type        string        `json:"type" example:"buy"`
+	Direction   string        `json:"direction,omitempty" gorm:"-" example:"outgoing"` // Transfers only: incoming, outgoing
+	Status      string        `json:"status" example:"completed"`
+	AssetID     uint          `json:"asset_id" example:"1"`
+	AssetSymbol string        `json:"asset_symbol" example:"BTC"`
+	Amount      money.Decimal `json:"amount" swaggertype:"string" example:"0.5"`
+	Price       money.Decimal `json:"price" swaggertype:"string" example:"50000.00"`
+	TotalValue  money.Decimal `json:"total_value" swaggertype:"string" example:"25000.00"`
+	UserID      uint          `json:"user_id" example:"1"`
+	RecipientID *uint         `json:"recipient_id,omitempty" example:"2"`
+	Description string        `json:"description" example:"Buying Bitcoin"`
+}
+
+// Holding represents a user's position in an asset. Quantity only changes when
+// a transaction completes; Reserved is the part of Quantity earmarked by pending
+// sells and transfers.
+type Holding struct {
+	ID        uint          `json:"id" gorm:"primaryKey" example:"1"`
+	UserID    uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_holdings_user_asset" example:"1"`
+	AssetID   uint          `json:"asset_id" gorm:"not null;uniqueIndex:idx_holdings_user_asset" example:"1"`
+	Quantity  money.Decimal `json:"quantity" gorm:"not null" swaggertype:"string" example:"1.5"`
+	Reserved  money.Decimal `json:"reserved" gorm:"not null" swaggertype:"string" example:"0.5"`
+	Available money.Decimal `json:"available" gorm:"-" swaggertype:"string" example:"1.0"` // Quantity - Reserved
+	Version   uint          `json:"-" gorm:"not null;default:0"`                           // Optimistic locking
+	CreatedAt time.Time     `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time     `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+
+	// Relationships
+	Asset Asset `json:"asset" gorm:"foreignKey:AssetID"`
+}
+
+// Cost-basis methods for matching sells against earlier buys
+const (
+	CostBasisFIFO    = "fifo"    // Oldest lots are sold first
+	CostBasisLIFO    = "lifo"    // Newest lots are sold first
+	CostBasisAverage = "average" // Every unit costs the running average
+)
+
+// Portfolio values a user's positions at a point in time
+type Portfolio struct {
+	UserID        uint                `json:"user_id" example:"1"`
+	AsOf          time.Time           `json:"as_of" example:"2023-01-01T00:00:00Z"`
+	Method        string              `json:"method" example:"fifo"` // fifo, lifo, average
+	MarketValue   money.Decimal       `json:"market_value" swaggertype:"string" example:"27500.00"`
+	CostBasis     money.Decimal       `json:"cost_basis" swaggertype:"string" example:"25000.00"`
+	RealizedPnL   money.Decimal       `json:"realized_pnl" swaggertype:"string" example:"1200.00"`
+	UnrealizedPnL money.Decimal       `json:"unrealized_pnl" swaggertype:"string" example:"2500.00"`
+	Positions     []PortfolioPosition `json:"positions"`
+}
+
+// PortfolioPosition is the valuation of one asset in a Portfolio. Positions
+// that have been closed are kept for their realized P&L.
+type PortfolioPosition struct {
+	AssetID       uint          `json:"asset_id" example:"1"`
+	Symbol        string        `json:"symbol" example:"BTC"`
+	Name          string        `json:"name" example:"Bitcoin"`
+	Quantity      money.Decimal `json:"quantity" swaggertype:"string" example:"0.5"`
+	AverageCost   money.Decimal `json:"average_cost" swaggertype:"string" example:"50000.00"`
+	CostBasis     money.Decimal `json:"cost_basis" swaggertype:"string" example:"25000.00"`
+	Price         money.Decimal `json:"price" swaggertype:"string" example:"55000.00"`
+	PriceAt       *time.Time    `json:"price_at" example:"2023-01-01T00:00:00Z"` // When Price was observed
+	MarketValue   money.Decimal `json:"market_value" swaggertype:"string" example:"27500.00"`
+	RealizedPnL   money.Decimal `json:"realized_pnl" swaggertype:"string" example:"1200.00"`
+	UnrealizedPnL money.Decimal `json:"unrealized_pnl" swaggertype:"string" example:"2500.00"`
+}
+
+// Capital gain terms
+const (
+	GainTermShort = "short"
+	GainTermLong  = "long"
+)
+
+// CapitalGain is the part of a sell matched with one tax lot
+type CapitalGain struct {
+	SellTransactionID uint          `json:"sell_transaction_id" example:"12"`
+	BuyTransactionID  *uint         `json:"buy_transaction_id" example:"3"` // Null when the sell exceeded the known lots
+	AssetID           uint          `json:"asset_id" example:"1"`
+	Symbol            string        `json:"symbol" example:"BTC"`
+	Quantity          money.Decimal `json:"quantity" swaggertype:"string" example:"0.5"`
+	AcquiredAt        *time.Time    `json:"acquired_at" example:"2022-03-01T10:00:00Z"`
+	SoldAt            time.Time     `json:"sold_at" example:"2023-06-01T10:00:00Z"`
+	Proceeds          money.Decimal `json:"proceeds" swaggertype:"string" example:"27500.00"`
+	CostBasis         money.Decimal `json:"cost_basis" swaggertype:"string" example:"20000.00"`
+	Gain              money.Decimal `json:"gain" swaggertype:"string" example:"7500.00"` // Negative for a loss
+	Term              string        `json:"term" example:"long"`                         // short, long
+}
+
+// CapitalGainsSummary totals the capital gains of one term
+type CapitalGainsSummary struct {
+	Proceeds  money.Decimal `json:"proceeds" swaggertype:"string" example:"27500.00"`
+	CostBasis money.Decimal `json:"cost_basis" swaggertype:"string" example:"20000.00"`
+	Gain      money.Decimal `json:"gain" swaggertype:"string" example:"7500.00"`
+}
+
+// CapitalGainsReport lists the capital gains a user realized in a tax year
+type CapitalGainsReport struct {
+	UserID    uint                `json:"user_id" example:"1"`
+	Year      int                 `json:"year" example:"2023"`
+	Method    string              `json:"method" example:"fifo"` // fifo, lifo
+	ShortTerm CapitalGainsSummary `json:"short_term"`
+	LongTerm  CapitalGainsSummary `json:"long_term"`
+	Total     CapitalGainsSummary `json:"total"`
+	Gains     []CapitalGain       `json:"gains"`
+}
+
+// RefreshToken represents a persisted, hashed refresh token. Tokens issued from
+// the same login share a FamilyID so that a replayed token can revoke the whole
+// chain for that device.
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	FamilyID     string     `json:"family_id" gorm:"not null;index"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	DeviceName   string     `json:"device_name"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RotatedAt    *time.Time `json:"rotated_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RevokedToken records a revoked access token. A row with an empty JTI revokes
+// every token issued to UserID up to CreatedAt.
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	JTI       string    `json:"jti" gorm:"index"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IdempotencyRecord stores the response to a request made with an
+// Idempotency-Key header so that retries can be answered without repeating the
+// request. UserID is 0 for unauthenticated requests, whose keys are hashed with
+// the client's address; a StatusCode of 0 marks a request that is still in
+// progress.
+type IdempotencyRecord struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key          string    `json:"key" gorm:"size:255;not null;uniqueIndex:idx_idempotency_user_key"`
+	Fingerprint  string    `json:"-" gorm:"not null"` // SHA-256 of method, path and body
+	StatusCode   int       `json:"status_code"`
+	ContentType  string    `json:"-"`
+	ResponseBody []byte    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // CreateUserRequest represents the request payload for creating a user
 type CreateUserRequest struct {
 	Email     string `json:"email" binding:"required,email" example:"user@example.com"`
@@ -69,50 +336,108 @@ type UpdateUserRequest struct {
 	Username  string `json:"username" example:"johndoe"`
 	FirstName string `json:"first_name" example:"John"`
 	LastName  string `json:"last_name" example:"Doe"`
+	Role      string `json:"role" binding:"omitempty,oneof=admin operator trader read-only" example:"trader"` // Admin only
 	IsActive  *bool  `json:"is_active" example:"true"`
 }
 
 // CreateAssetRequest represents the request payload for creating an asset
 type CreateAssetRequest struct {
-	Name        string  `json:"name" binding:"required" example:"Bitcoin"`
-	Symbol      string  `json:"symbol" binding:"required" example:"BTC"`
-	Type        string  `json:"type" binding:"required" example:"cryptocurrency"`
-	Description string  `json:"description" example:"Digital currency"`
-	Price       float64 `json:"price" binding:"required,min=0" example:"50000.00"`
+	Name          string        `json:"name" binding:"required" example:"Bitcoin"`
+	Symbol        string        `json:"symbol" binding:"required" example:"BTC"`
+	Type          string        `json:"type" binding:"required" example:"cryptocurrency"`
+	Description   string        `json:"description" example:"Digital currency"`
+	Price         money.Decimal `json:"price" binding:"required,min=0" swaggertype:"string" example:"50000.00"`
+	QuantityScale *int32        `json:"quantity_scale" binding:"omitempty,min=0,max=18" example:"8"`
+	PriceScale    *int32        `json:"price_scale" binding:"omitempty,min=0,max=18" example:"2"`
 }
 
 // UpdateAssetRequest represents the request payload for updating an asset
 type UpdateAssetRequest struct {
-	Name        string  `json:"name" example:"Bitcoin"`
-	Symbol      string  `json:"symbol" example:"BTC"`
-	Type        string  `json:"type" example:"cryptocurrency"`
-	Description string  `json:"description" example:"Digital currency"`
-	Price       float64 `json:"price" example:"50000.00"`
-	IsActive    *bool   `json:"is_active" example:"true"`
+	Name          string        `json:"name" example:"Bitcoin"`
+	Symbol        string        `json:"symbol" example:"BTC"`
+	Type          string        `json:"type" example:"cryptocurrency"`
+	Description   string        `json:"description" example:"Digital currency"`
+	Price         money.Decimal `json:"price" swaggertype:"string" example:"50000.00"`
+	QuantityScale *int32        `json:"quantity_scale" binding:"omitempty,min=0,max=18" example:"8"`
+	PriceScale    *int32        `json:"price_scale" binding:"omitempty,min=0,max=18" example:"2"`
+	IsActive      *bool         `json:"is_active" example:"true"`
+}
+
+// Asset import row actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+)
+
+// AssetImportRow is the result of one row of an asset import
+type AssetImportRow struct {
+	Row     int      `json:"row" example:"2"` // 1-based row number; CSV rows count the header
+	Symbol  string   `json:"symbol" example:"BTC"`
+	Action  string   `json:"action" example:"update"`        // create, update, unchanged, error
+	AssetID *uint    `json:"asset_id,omitempty" example:"1"` // Omitted for errors and dry-run creates
+	Changes []string `json:"changes,omitempty" example:"price,description"`
+	Errors  []string `json:"errors,omitempty" example:"price is required"`
+}
+
+// AssetImportSummary counts the rows of an asset import by action
+type AssetImportSummary struct {
+	Total     int `json:"total" example:"120"`
+	Created   int `json:"created" example:"100"`
+	Updated   int `json:"updated" example:"15"`
+	Unchanged int `json:"unchanged" example:"5"`
+	Failed    int `json:"failed" example:"0"`
+}
+
+// AssetImportReport is the response of an asset import. Applied is false
+// for dry runs and for atomic imports in which a row failed.
+type AssetImportReport struct {
+	DryRun  bool               `json:"dry_run" example:"false"`
+	Upsert  bool               `json:"upsert" example:"true"`
+	Atomic  bool               `json:"atomic" example:"true"`
+	Applied bool               `json:"applied" example:"true"`
+	Summary AssetImportSummary `json:"summary"`
+	Rows    []AssetImportRow   `json:"rows"`
 }
 
 // CreateTransactionRequest represents the request payload for creating a transaction
 type CreateTransactionRequest struct {
-	AssetID     uint    `json:"asset_id" binding:"required" example:"1"`
-	Type        string  `json:"type" binding:"required,oneof=buy sell transfer" example:"buy"`
-	Amount      float64 `json:"amount" binding:"required,min=0" example:"0.5"`
-	Price       float64 `json:"price" binding:"required,min=0" example:"50000.00"`
-	Description string  `json:"description" example:"Buying Bitcoin"`
+	AssetID           uint          `json:"asset_id" binding:"required" example:"1"`
+	Type              string        `json:"type" binding:"required,oneof=buy sell transfer" example:"buy"`
+	Amount            money.Decimal `json:"amount" binding:"required,min=0" swaggertype:"string" example:"0.5"`
+	Price             money.Decimal `json:"price" binding:"required,min=0" swaggertype:"string" example:"50000.00"`
+	Description       string        `json:"description" example:"Buying Bitcoin"`
+	RecipientID       uint          `json:"recipient_id" example:"2"`             // Transfers: recipient by ID...
+	RecipientUsername string        `json:"recipient_username" example:"janedoe"` // ...or by username
 }
 
 // UpdateTransactionRequest represents the request payload for updating a transaction
+// Type, Amount and Price can only be changed while the transaction is pending.
+// Status changes follow the same rules as the action endpoints.
 type UpdateTransactionRequest struct {
-	Type        string  `json:"type" example:"buy"`
-	Amount      float64 `json:"amount" example:"0.5"`
-	Price       float64 `json:"price" example:"50000.00"`
-	Status      string  `json:"status" example:"completed"`
-	Description string  `json:"description" example:"Buying Bitcoin"`
+	Type        string        `json:"type" binding:"omitempty,oneof=buy sell transfer" example:"buy"`
+	Amount      money.Decimal `json:"amount" swaggertype:"string" example:"0.5"`
+	Price       money.Decimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Status      string        `json:"status" binding:"omitempty,oneof=pending completed failed cancelled" example:"completed"`
+	Description string        `json:"description" example:"Buying Bitcoin"`
+}
+
+// TransactionActionRequest represents the optional payload of a transaction status action
+type TransactionActionRequest struct {
+	Reason string `json:"reason" example:"Order expired"`
 }
 
 // LoginRequest represents the request payload for user login
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email" example:"user@example.com"`
-	Password string `json:"password" binding:"required" example:"password123"`
+	Email      string `json:"email" binding:"required,email" example:"user@example.com"`
+	Password   string `json:"password" binding:"required" example:"password123"`
+	DeviceName string `json:"device_name" example:"iPhone 15"`
+}
+
+// RefreshRequest represents the request payload for refreshing an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q1w2e3r4t5y6..."`
 }
 
 // RegisterRequest represents the request payload for user registration
@@ -124,14 +449,71 @@ type RegisterRequest struct {
 	LastName  string `json:"last_name" example:"Doe"`
 }
 
+// LogoutRequest represents the optional request payload for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" example:"q1w2e3r4t5y6..."`
+}
+
 // AuthResponse represents the response payload for authentication
 type AuthResponse struct {
-	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	User  User   `json:"user"`
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q1w2e3r4t5y6..."`
+	ExpiresIn    int64  `json:"expires_in" example:"900"` // Access token lifetime in seconds
+	User         User   `json:"user"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error   string `json:"error" example:"Invalid request"`
-	Message string `json:"message" example:"The request body is invalid"`
+	Error     string `json:"error" example:"Invalid request"`
+	Message   string `json:"message" example:"The request body is invalid"`
+	RequestID string `json:"request_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"` // Quote when reporting a problem
+	TraceID   string `json:"trace_id,omitempty" example:"0af7651916cd43dd8448eb211c80319c"`   // Set when the request is traced
+}
+
+// NewErrorResponse creates an ErrorResponse quoting the request and trace IDs
+// of ctx, which may be a request's context or its *gin.Context
+func NewErrorResponse(ctx context.Context, err, message string) ErrorResponse {
+	return ErrorResponse{
+		Error:     err,
+		Message:   message,
+		RequestID: logging.RequestID(ctx),
+		TraceID:   logging.TraceID(ctx),
+	}
+}
+
+// ListResponse is the envelope of list endpoints
+type ListResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// Pagination describes the page returned by a list endpoint. Next is a link
+// to the following page and is empty on the last page.
+type Pagination struct {
+	Total      int64  `json:"total" example:"120"`
+	Limit      int    `json:"limit" example:"50"`
+	Offset     int    `json:"offset" example:"0"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbNTBdfQ"`
+	Next       string `json:"next,omitempty" example:"/api/v1/assets?cursor=eyJzIjoiaWQiLCJ2IjpbNTBdfQ&limit=50"`
+}
+
+// Health statuses
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+	HealthSkip = "skipped"
+)
+
+// HealthCheck is the outcome of one readiness check
+type HealthCheck struct {
+	Status   string `json:"status" example:"ok"` // ok, fail or skipped
+	Duration string `json:"duration" example:"1.2ms"`
+	Detail   string `json:"detail,omitempty" example:"version 12 of 12"`
+	Error    string `json:"error,omitempty" example:"context deadline exceeded"`
+}
+
+// HealthResponse is the body of the health endpoints
+type HealthResponse struct {
+	Status string                 `json:"status" example:"ok"` // ok or fail
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
 }
@@ -0,0 +1,262 @@
+// Package money provides an exact decimal type for prices, amounts and values.
+//
+// Decimals are stored as NUMERIC in PostgreSQL and as TEXT in SQLite, and are
+// serialized to JSON as strings so that clients never round-trip them through
+// binary floating point.
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Decimal is an arbitrary-precision decimal number. The zero value is 0.
+type Decimal struct {
+	d decimal.Decimal
+}
+
+// Zero is the decimal 0
+var Zero = Decimal{}
+
+// Limits of the numeric(36,18) columns decimals are stored in
+const (
+	// MaxScale is the most digits a decimal may have after the decimal point
+	MaxScale = 18
+	// MaxIntegerDigits is the most digits a decimal may have before the decimal point
+	MaxIntegerDigits = 18
+	// maxStringLength bounds the text NewFromString parses, which leaves room
+	// for exponents and trailing zeros
+	maxStringLength = 64
+)
+
+// maxMagnitude is the smallest absolute value that is too large to store
+var maxMagnitude = decimal.New(1, MaxIntegerDigits)
+
+// NewFromString parses a decimal such as "123.45" or "-1e-8". Values that do
+// not fit numeric(36,18) are rejected, so that huge exponents never reach
+// arithmetic whose cost grows with them.
+func NewFromString(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > maxStringLength {
+		return Decimal{}, fmt.Errorf("invalid decimal: longer than %d characters", maxStringLength)
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	if d.IsZero() {
+		return Zero, nil
+	}
+	// The coefficient has fewer than maxStringLength digits, so an exponent
+	// outside this range always leaves too many digits on one side
+	if exp := d.Exponent(); exp > MaxIntegerDigits || exp < -(maxStringLength+MaxScale) {
+		return Decimal{}, fmt.Errorf("decimal %q is out of range", s)
+	}
+	if d.Abs().Cmp(maxMagnitude) >= 0 {
+		return Decimal{}, fmt.Errorf("decimal %q has more than %d digits before the decimal point", s, MaxIntegerDigits)
+	}
+	if !d.Truncate(MaxScale).Equal(d) {
+		return Decimal{}, fmt.Errorf("decimal %q has more than %d digits after the decimal point", s, MaxScale)
+	}
+	return Decimal{d: d}, nil
+}
+
+// RequireFromString parses a decimal and panics if it is invalid. It is meant
+// for constants and tests.
+func RequireFromString(s string) Decimal {
+	d, err := NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewFromInt returns the decimal value of i
+func NewFromInt(i int64) Decimal {
+	return Decimal{d: decimal.NewFromInt(i)}
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{d: d.d.Add(other.d)}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{d: d.d.Sub(other.d)}
+}
+
+// Mul returns d * other
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{d: d.d.Mul(other.d)}
+}
+
+// Div returns d / other rounded to scale decimal places with the given mode.
+// The quotient is rounded once, from the exact remainder of the division. It
+// panics if other is zero.
+func (d Decimal) Div(other Decimal, scale int32, mode RoundingMode) Decimal {
+	// q is truncated toward zero and r has the sign of d
+	q, r := d.d.QuoRem(other.d, scale)
+	if r.IsZero() || mode == RoundDown {
+		return Decimal{d: q}
+	}
+
+	awayFromZero := mode == RoundUp
+	if mode != RoundUp {
+		// Compare the remainder with half of one unit in the last place
+		unit := decimal.New(1, -scale)
+		switch r.Abs().Mul(decimal.NewFromInt(2)).Cmp(other.d.Abs().Mul(unit)) {
+		case 1:
+			awayFromZero = true
+		case 0:
+			awayFromZero = mode == RoundHalfUp || q.Shift(scale).BigInt().Bit(0) == 1
+		}
+	}
+	if !awayFromZero {
+		return Decimal{d: q}
+	}
+	unit := decimal.New(int64(d.d.Sign()*other.d.Sign()), -scale)
+	return Decimal{d: q.Add(unit)}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{d: d.d.Neg()}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{d: d.d.Abs()}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1
+func (d Decimal) Cmp(other Decimal) int {
+	return d.d.Cmp(other.d)
+}
+
+// Equal reports whether d == other
+func (d Decimal) Equal(other Decimal) bool {
+	return d.d.Equal(other.d)
+}
+
+// GreaterThan reports whether d > other
+func (d Decimal) GreaterThan(other Decimal) bool {
+	return d.d.GreaterThan(other.d)
+}
+
+// LessThan reports whether d < other
+func (d Decimal) LessThan(other Decimal) bool {
+	return d.d.LessThan(other.d)
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d
+func (d Decimal) Sign() int {
+	return d.d.Sign()
+}
+
+// IsZero reports whether d == 0
+func (d Decimal) IsZero() bool {
+	return d.d.IsZero()
+}
+
+// IsPositive reports whether d > 0
+func (d Decimal) IsPositive() bool {
+	return d.d.IsPositive()
+}
+
+// IsNegative reports whether d < 0
+func (d Decimal) IsNegative() bool {
+	return d.d.IsNegative()
+}
+
+// Min returns the smaller of d and other
+func (d Decimal) Min(other Decimal) Decimal {
+	if other.LessThan(d) {
+		return other
+	}
+	return d
+}
+
+// Float64 returns the nearest float64. It is only meant for logging and metrics.
+func (d Decimal) Float64() float64 {
+	f, _ := d.d.Float64()
+	return f
+}
+
+// String returns the shortest exact decimal representation, e.g. "50000.5"
+func (d Decimal) String() string {
+	return d.d.String()
+}
+
+// StringFixed returns d with exactly places digits after the decimal point
+func (d Decimal) StringFixed(places int32) string {
+	return d.d.StringFixed(places)
+}
+
+// MarshalJSON encodes the decimal as a JSON string
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts either a JSON string ("12.5") or a JSON number (12.5).
+// Numbers are parsed from their literal text, never through float64.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	parsed, err := NewFromString(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner
+func (d *Decimal) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = Zero
+		return nil
+	case string:
+		parsed, err := NewFromString(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	case int64:
+		*d = NewFromInt(v)
+		return nil
+	case float64:
+		// Only reachable for values written before the column held exact decimals
+		*d = Decimal{d: decimal.NewFromFloat(v)}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into money.Decimal", value)
+}
+
+// Value implements driver.Valuer. Decimals are written as their exact string form.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// GormDBDataType stores decimals as NUMERIC in PostgreSQL and TEXT in SQLite,
+// which has no exact numeric type
+func (Decimal) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "numeric(36,18)"
+	default:
+		return "text"
+	}
+}
@@ -0,0 +1,61 @@
+package money
+
+import "fmt"
+
+// RoundingMode selects how values are rounded to an asset's scale
+type RoundingMode string
+
+const (
+	// RoundHalfEven rounds to the nearest value, ties to the even digit (banker's rounding)
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundHalfUp rounds to the nearest value, ties away from zero
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundDown truncates toward zero
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero
+	RoundUp RoundingMode = "up"
+)
+
+// DefaultRoundingMode is used when no rounding mode is configured
+const DefaultRoundingMode = RoundHalfEven
+
+// ParseRoundingMode validates a configured rounding mode
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch mode := RoundingMode(s); mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp:
+		return mode, nil
+	case "":
+		return DefaultRoundingMode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q (expected half_even, half_up, down or up)", s)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (m RoundingMode) MarshalText() ([]byte, error) {
+	return []byte(m), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so that configured
+// rounding modes are validated when they are read
+func (m *RoundingMode) UnmarshalText(text []byte) error {
+	mode, err := ParseRoundingMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// Round returns d rounded to scale digits after the decimal point
+func (d Decimal) Round(scale int32, mode RoundingMode) Decimal {
+	switch mode {
+	case RoundHalfUp:
+		return Decimal{d: d.d.Round(scale)}
+	case RoundDown:
+		return Decimal{d: d.d.RoundDown(scale)}
+	case RoundUp:
+		return Decimal{d: d.d.RoundUp(scale)}
+	default:
+		return Decimal{d: d.d.RoundBank(scale)}
+	}
+}
@@ -0,0 +1,134 @@
+package portfolio
+
+import (
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// LotMethods are the cost-basis methods that match sells with specific tax
+// lots; the average method has no lots
+var LotMethods = map[string]bool{
+	models.CostBasisFIFO: true,
+	models.CostBasisLIFO: true,
+}
+
+// isLongTerm reports whether a lot acquired at acquired and sold at sold was
+// held for more than a year
+func isLongTerm(acquired, sold time.Time) bool {
+	return sold.After(acquired.AddDate(1, 0, 0))
+}
+
+// CapitalGains matches the user's sells with their tax lots and reports the
+// gains realized by sells that completed in year (UTC). Lots are built from
+// the whole history, so sells in year can match lots bought in earlier years.
+// Incoming transfers start new lots at the transfer time and price.
+func CapitalGains(db *gorm.DB, userID uint, year int, method string, mode money.RoundingMode) (*models.CapitalGainsReport, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0).Add(-time.Nanosecond)
+	events, err := LoadEvents(db, userID, &end)
+	if err != nil {
+		return nil, err
+	}
+
+	var assetIDs []uint
+	positions := map[uint]*position{}
+	for _, e := range events {
+		if _, ok := positions[e.AssetID]; !ok {
+			positions[e.AssetID] = newPosition(method)
+			assetIDs = append(assetIDs, e.AssetID)
+		}
+	}
+	var assets []models.Asset
+	if len(assetIDs) > 0 {
+		if err := db.Unscoped().Find(&assets, assetIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	assetsByID := make(map[uint]models.Asset, len(assets))
+	for _, a := range assets {
+		assetsByID[a.ID] = a
+	}
+
+	report := &models.CapitalGainsReport{
+		UserID:    userID,
+		Year:      year,
+		Method:    method,
+		ShortTerm: newSummary(),
+		LongTerm:  newSummary(),
+		Total:     newSummary(),
+		Gains:     []models.CapitalGain{},
+	}
+	for _, e := range events {
+		p := positions[e.AssetID]
+		switch {
+		case e.Type == models.TransactionTypeBuy || e.Incoming:
+			p.add(e.Amount, e.Price, e.CompletedAt, e.ID)
+			continue
+		case e.Type != models.TransactionTypeSell:
+			p.remove(e.Amount, mode) // outgoing transfers are not taxable sales
+			continue
+		}
+
+		_, matched := p.remove(e.Amount, mode)
+		if e.CompletedAt.Before(start) {
+			continue
+		}
+
+		asset := assetsByID[e.AssetID]
+		scale := asset.PricingScale()
+		gain := func(l *lot, quantity money.Decimal) models.CapitalGain {
+			g := models.CapitalGain{
+				SellTransactionID: e.ID,
+				AssetID:           e.AssetID,
+				Symbol:            asset.Symbol,
+				Quantity:          quantity,
+				SoldAt:            e.CompletedAt.UTC(),
+				Proceeds:          quantity.Mul(e.Price).Round(scale, mode),
+				CostBasis:         money.Zero,
+				Term:              models.GainTermShort,
+			}
+			if l != nil {
+				id, acquired := l.transactionID, l.acquiredAt.UTC()
+				g.BuyTransactionID = &id
+				g.AcquiredAt = &acquired
+				g.CostBasis = quantity.Mul(l.unitCost).Round(scale, mode)
+				if isLongTerm(acquired, g.SoldAt) {
+					g.Term = models.GainTermLong
+				}
+			}
+			g.Gain = g.Proceeds.Sub(g.CostBasis)
+			return g
+		}
+
+		unmatched := e.Amount
+		for i := range matched {
+			report.Gains = append(report.Gains, gain(&matched[i], matched[i].quantity))
+			unmatched = unmatched.Sub(matched[i].quantity)
+		}
+		if unmatched.IsPositive() {
+			// Sold more than the known lots; reported without a cost basis
+			report.Gains = append(report.Gains, gain(nil, unmatched))
+		}
+	}
+
+	for _, g := range report.Gains {
+		summary := &report.ShortTerm
+		if g.Term == models.GainTermLong {
+			summary = &report.LongTerm
+		}
+		for _, s := range []*models.CapitalGainsSummary{summary, &report.Total} {
+			s.Proceeds = s.Proceeds.Add(g.Proceeds)
+			s.CostBasis = s.CostBasis.Add(g.CostBasis)
+			s.Gain = s.Gain.Add(g.Gain)
+		}
+	}
+	return report, nil
+}
+
+func newSummary() models.CapitalGainsSummary {
+	return models.CapitalGainsSummary{Proceeds: money.Zero, CostBasis: money.Zero, Gain: money.Zero}
+}
@@ -0,0 +1,82 @@
+package portfolio
+
+import (
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+)
+
+// costScale is the precision of costs that have to be divided (average cost),
+// matching the precision of decimal columns
+const costScale = 18
+
+// lot is a quantity of an asset acquired at one unit cost
+type lot struct {
+	quantity      money.Decimal
+	unitCost      money.Decimal
+	acquiredAt    time.Time
+	transactionID uint // the buy or incoming transfer that acquired the lot
+}
+
+// position tracks the open lots of one asset and the P&L realized on it
+type position struct {
+	method   string
+	lots     []lot         // open lots, oldest first; unused by the average method
+	quantity money.Decimal // open quantity
+	cost     money.Decimal // cost of the open quantity
+	realized money.Decimal
+}
+
+func newPosition(method string) *position {
+	return &position{method: method, quantity: money.Zero, cost: money.Zero, realized: money.Zero}
+}
+
+// add acquires quantity at unitCost
+func (p *position) add(quantity, unitCost money.Decimal, at time.Time, transactionID uint) {
+	p.quantity = p.quantity.Add(quantity)
+	p.cost = p.cost.Add(quantity.Mul(unitCost))
+	if p.method != models.CostBasisAverage {
+		p.lots = append(p.lots, lot{quantity: quantity, unitCost: unitCost, acquiredAt: at, transactionID: transactionID})
+	}
+}
+
+// remove disposes of quantity and returns its cost and, unless the method is
+// average, the parts of the lots it was matched with. Quantity beyond the open
+// position (which the holdings checks normally prevent) is removed at no cost
+// and matched with no lot.
+func (p *position) remove(quantity money.Decimal, mode money.RoundingMode) (money.Decimal, []lot) {
+	quantity = quantity.Min(p.quantity)
+	if !quantity.IsPositive() {
+		return money.Zero, nil
+	}
+
+	cost := money.Zero
+	var matched []lot
+	switch {
+	case p.method == models.CostBasisAverage && quantity.Equal(p.quantity):
+		cost = p.cost
+	case p.method == models.CostBasisAverage:
+		cost = p.cost.Mul(quantity).Div(p.quantity, costScale, mode)
+	default:
+		for remaining := quantity; remaining.IsPositive(); {
+			i := 0
+			if p.method == models.CostBasisLIFO {
+				i = len(p.lots) - 1
+			}
+			taken := p.lots[i]
+			taken.quantity = remaining.Min(taken.quantity)
+			matched = append(matched, taken)
+			cost = cost.Add(taken.quantity.Mul(taken.unitCost))
+			remaining = remaining.Sub(taken.quantity)
+			p.lots[i].quantity = p.lots[i].quantity.Sub(taken.quantity)
+			if p.lots[i].quantity.IsZero() {
+				p.lots = append(p.lots[:i], p.lots[i+1:]...)
+			}
+		}
+	}
+
+	p.quantity = p.quantity.Sub(quantity)
+	p.cost = p.cost.Sub(cost)
+	return cost, matched
+}
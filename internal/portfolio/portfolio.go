@@ -0,0 +1,211 @@
+// Package portfolio values a user's positions and computes their P&L.
+//
+// Positions are rebuilt from the user's completed transactions in the order
+// they completed: buys and incoming transfers add lots at the transaction
+// price, sells and outgoing transfers remove lots according to the cost-basis
+// method. Only sells realize P&L; a transfer moves its cost basis to the
+// recipient at the transfer price. Open positions are valued at the asset's
+// current price or, for a historical snapshot, at the last price observed
+// before the snapshot time.
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Methods are the supported cost-basis methods
+var Methods = map[string]bool{
+	models.CostBasisFIFO:    true,
+	models.CostBasisLIFO:    true,
+	models.CostBasisAverage: true,
+}
+
+// Event is a completed transaction that changed a user's position
+type Event struct {
+	models.Transaction
+	CompletedAt time.Time
+	// Incoming is set for transfers the user received
+	Incoming bool
+}
+
+// LoadEvents returns the transactions of userID that completed at or before
+// asOf, in the order they completed. A nil asOf loads all of them.
+func LoadEvents(db *gorm.DB, userID uint, asOf *time.Time) ([]Event, error) {
+	var transactions []models.Transaction
+	if err := db.Where("status = ? AND (user_id = ? OR (type = ? AND recipient_id = ?))",
+		models.TransactionStatusCompleted, userID, models.TransactionTypeTransfer, userID).
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(transactions))
+	for i, t := range transactions {
+		ids[i] = t.ID
+	}
+	var changes []models.TransactionStatusChange
+	if err := db.Where("transaction_id IN ? AND to_status = ?", ids, models.TransactionStatusCompleted).
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	completedAt := make(map[uint]time.Time, len(changes))
+	for _, change := range changes {
+		completedAt[change.TransactionID] = change.CreatedAt
+	}
+
+	events := make([]Event, 0, len(transactions))
+	for _, t := range transactions {
+		at, ok := completedAt[t.ID]
+		if !ok {
+			// Completed before status changes were recorded
+			at = t.UpdatedAt
+		}
+		if asOf != nil && at.After(*asOf) {
+			continue
+		}
+		incoming := t.Type == models.TransactionTypeTransfer && t.UserID != userID
+		events = append(events, Event{Transaction: t, CompletedAt: at, Incoming: incoming})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].CompletedAt.Equal(events[j].CompletedAt) {
+			return events[i].CompletedAt.Before(events[j].CompletedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// observation is a price seen at a time
+type observation struct {
+	price money.Decimal
+	at    time.Time
+}
+
+// Compute values the portfolio of userID using method. A nil asOf values it
+// now at current prices.
+func Compute(db *gorm.DB, userID uint, method string, asOf *time.Time, mode money.RoundingMode) (*models.Portfolio, error) {
+	events, err := LoadEvents(db, userID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := map[uint]*position{}
+	lastTrade := map[uint]observation{}
+	var assetIDs []uint
+	for _, e := range events {
+		p, ok := positions[e.AssetID]
+		if !ok {
+			p = newPosition(method)
+			positions[e.AssetID] = p
+			assetIDs = append(assetIDs, e.AssetID)
+		}
+
+		switch {
+		case e.Type == models.TransactionTypeBuy || e.Incoming:
+			p.add(e.Amount, e.Price, e.CompletedAt, e.ID)
+		case e.Type == models.TransactionTypeSell:
+			cost, _ := p.remove(e.Amount, mode)
+			p.realized = p.realized.Add(e.Amount.Mul(e.Price).Sub(cost))
+		default: // outgoing transfer
+			p.remove(e.Amount, mode)
+		}
+		if e.Type != models.TransactionTypeTransfer {
+			lastTrade[e.AssetID] = observation{price: e.Price, at: e.CompletedAt}
+		}
+	}
+	sort.Slice(assetIDs, func(i, j int) bool { return assetIDs[i] < assetIDs[j] })
+
+	// Deleted assets still belong to the user's history
+	var assets []models.Asset
+	if len(assetIDs) > 0 {
+		if err := db.Unscoped().Find(&assets, assetIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	assetsByID := make(map[uint]models.Asset, len(assets))
+	for _, a := range assets {
+		assetsByID[a.ID] = a
+	}
+
+	result := &models.Portfolio{
+		UserID:        userID,
+		Method:        method,
+		AsOf:          time.Now().UTC(),
+		MarketValue:   money.Zero,
+		CostBasis:     money.Zero,
+		RealizedPnL:   money.Zero,
+		UnrealizedPnL: money.Zero,
+		Positions:     []models.PortfolioPosition{},
+	}
+	if asOf != nil {
+		result.AsOf = asOf.UTC()
+	}
+
+	for _, id := range assetIDs {
+		asset := assetsByID[id]
+		p := positions[id]
+
+		price := observation{price: asset.Price}
+		if asset.PriceUpdatedAt != nil {
+			price.at = *asset.PriceUpdatedAt
+		}
+		if asOf != nil {
+			if price, err = priceAt(db, id, *asOf, lastTrade[id]); err != nil {
+				return nil, err
+			}
+		}
+
+		scale := asset.PricingScale()
+		position := models.PortfolioPosition{
+			AssetID:     id,
+			Symbol:      asset.Symbol,
+			Name:        asset.Name,
+			Quantity:    p.quantity,
+			AverageCost: money.Zero,
+			CostBasis:   p.cost.Round(scale, mode),
+			Price:       price.price,
+			MarketValue: p.quantity.Mul(price.price).Round(scale, mode),
+			RealizedPnL: p.realized.Round(scale, mode),
+		}
+		if !price.at.IsZero() {
+			at := price.at.UTC()
+			position.PriceAt = &at
+		}
+		if p.quantity.IsPositive() {
+			position.AverageCost = p.cost.Div(p.quantity, scale, mode)
+		}
+		position.UnrealizedPnL = position.MarketValue.Sub(position.CostBasis)
+
+		result.MarketValue = result.MarketValue.Add(position.MarketValue)
+		result.CostBasis = result.CostBasis.Add(position.CostBasis)
+		result.RealizedPnL = result.RealizedPnL.Add(position.RealizedPnL)
+		result.UnrealizedPnL = result.UnrealizedPnL.Add(position.UnrealizedPnL)
+		result.Positions = append(result.Positions, position)
+	}
+	return result, nil
+}
+
+// priceAt returns the last price of an asset observed at or before asOf: the
+// latest price history entry or completed trade
+func priceAt(db *gorm.DB, assetID uint, asOf time.Time, lastTrade observation) (observation, error) {
+	var entries []models.AssetPrice
+	if err := db.Where("asset_id = ? AND recorded_at <= ?", assetID, asOf.UTC()).
+		Order("recorded_at DESC").Order("id DESC").Limit(1).Find(&entries).Error; err != nil {
+		return observation{}, err
+	}
+	if len(entries) == 1 && !entries[0].RecordedAt.Before(lastTrade.at) {
+		return observation{price: entries[0].Price, at: entries[0].RecordedAt}, nil
+	}
+	if lastTrade.at.IsZero() {
+		return observation{price: money.Zero}, nil
+	}
+	return lastTrade, nil
+}
@@ -0,0 +1,159 @@
+package pricefeed
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go-api-test1/internal/money"
+)
+
+// FileProvider quotes prices from a local CSV or JSON file, chosen by the file
+// extension. The file is re-read whenever it changes, so another process can
+// keep it up to date.
+//
+// CSV files have the columns symbol, price and optionally time (RFC 3339),
+// with an optional header row. JSON files hold either an object mapping
+// symbols to prices, e.g. {"BTC": "50000.00"}, or an array of objects with
+// symbol, price and optionally time fields. Prices may be strings or numbers.
+// Quotes without a time are dated with the file's modification time.
+type FileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	quotes  map[string]Quote
+}
+
+// NewFileProvider creates a FileProvider reading path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name implements PriceProvider
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Quote implements PriceProvider
+func (p *FileProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.reload(); err != nil {
+		return Quote{}, err
+	}
+	quote, ok := p.quotes[strings.ToUpper(symbol)]
+	if !ok {
+		return Quote{}, ErrSymbolNotFound
+	}
+	return quote, nil
+}
+
+// reload parses the file if it changed since it was last read
+func (p *FileProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if p.quotes != nil && info.ModTime().Equal(p.modTime) {
+		return nil
+	}
+
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []fileEntry
+	switch strings.ToLower(filepath.Ext(p.path)) {
+	case ".csv":
+		entries, err = readCSV(f)
+	case ".json":
+		entries, err = readJSON(f)
+	default:
+		err = fmt.Errorf("unsupported file type %q, expected .csv or .json", filepath.Ext(p.path))
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", p.path, err)
+	}
+
+	quotes := make(map[string]Quote, len(entries))
+	for _, entry := range entries {
+		price, err := money.NewFromString(entry.Price.String())
+		if err != nil {
+			return fmt.Errorf("reading %s: %s: invalid price %q", p.path, entry.Symbol, entry.Price)
+		}
+		at := info.ModTime()
+		if entry.Time != "" {
+			if at, err = time.Parse(time.RFC3339, entry.Time); err != nil {
+				return fmt.Errorf("reading %s: %s: invalid time %q", p.path, entry.Symbol, entry.Time)
+			}
+		}
+		quotes[strings.ToUpper(entry.Symbol)] = Quote{Price: price, At: at}
+	}
+	p.quotes = quotes
+	p.modTime = info.ModTime()
+	return nil
+}
+
+// fileEntry is a row of a price file
+type fileEntry struct {
+	Symbol string      `json:"symbol"`
+	Price  json.Number `json:"price"`
+	Time   string      `json:"time"`
+}
+
+func readCSV(r io.Reader) ([]fileEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(record[0], "symbol") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected symbol,price[,time]", i+1)
+		}
+		entry := fileEntry{Symbol: record[0], Price: json.Number(record[1])}
+		if len(record) > 2 {
+			entry.Time = record[2]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readJSON(r io.Reader) ([]fileEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+	var bySymbol map[string]json.Number
+	if err := json.Unmarshal(data, &bySymbol); err != nil {
+		return nil, fmt.Errorf("expected an object of symbol prices or an array of quotes")
+	}
+	for symbol, price := range bySymbol {
+		entries = append(entries, fileEntry{Symbol: symbol, Price: price})
+	}
+	return entries, nil
+}
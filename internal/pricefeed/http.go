@@ -0,0 +1,140 @@
+package pricefeed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api-test1/internal/money"
+)
+
+// maxResponseSize bounds the response body read from a price endpoint
+const maxResponseSize = 1 << 20
+
+// HTTPProvider quotes prices from an HTTP endpoint that returns JSON.
+//
+// The URL template contains a {symbol} placeholder, e.g.
+// https://prices.example.com/v1/quotes/{symbol}. The price is read from the
+// field at PriceField, a dot-separated path such as "data.price", and may be a
+// string or a number. If TimeField is set, the quote is dated with that field,
+// an RFC 3339 string or Unix seconds; otherwise with the time it was fetched.
+// A 404 response means the endpoint does not know the symbol.
+type HTTPProvider struct {
+	URLTemplate string
+	PriceField  string
+	TimeField   string
+	Header      http.Header // Sent with every request, e.g. an API key
+	Client      *http.Client
+}
+
+// NewHTTPProvider creates an HTTPProvider with a client using timeout
+func NewHTTPProvider(urlTemplate, priceField, timeField string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		URLTemplate: urlTemplate,
+		PriceField:  priceField,
+		TimeField:   timeField,
+		Header:      http.Header{},
+		Client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements PriceProvider
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Quote implements PriceProvider
+func (p *HTTPProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	endpoint := strings.ReplaceAll(p.URLTemplate, "{symbol}", url.PathEscape(symbol))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	for key, values := range p.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	fetchedAt := time.Now()
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Quote{}, ErrSymbolNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return Quote{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep prices exact
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return Quote{}, fmt.Errorf("%s returned invalid JSON: %w", endpoint, err)
+	}
+
+	rawPrice, ok := lookup(document, p.PriceField)
+	if !ok {
+		return Quote{}, fmt.Errorf("%s: response has no %q field", endpoint, p.PriceField)
+	}
+	price, err := money.NewFromString(fmt.Sprint(rawPrice))
+	if err != nil {
+		return Quote{}, fmt.Errorf("%s: invalid price %v", endpoint, rawPrice)
+	}
+
+	quote := Quote{Price: price, At: fetchedAt}
+	if p.TimeField != "" {
+		rawTime, ok := lookup(document, p.TimeField)
+		if !ok {
+			return Quote{}, fmt.Errorf("%s: response has no %q field", endpoint, p.TimeField)
+		}
+		if quote.At, err = parseQuoteTime(rawTime); err != nil {
+			return Quote{}, fmt.Errorf("%s: invalid time %v", endpoint, rawTime)
+		}
+	}
+	return quote, nil
+}
+
+// lookup follows a dot-separated path of object keys
+func lookup(document interface{}, path string) (interface{}, bool) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok || value == nil {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// parseQuoteTime accepts an RFC 3339 string or a number of Unix seconds
+func parseQuoteTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		return time.Parse(time.RFC3339, v)
+	case json.Number:
+		seconds, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported time %v", value)
+}
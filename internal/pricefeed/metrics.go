@@ -0,0 +1,52 @@
+package pricefeed
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics counts the work of a Worker since it started
+type Metrics struct {
+	Provider         string     `json:"provider" example:"http"`
+	Polls            uint64     `json:"polls" example:"42"`       // Completed polling rounds
+	Quotes           uint64     `json:"quotes" example:"420"`     // Quotes received
+	Updates          uint64     `json:"updates" example:"97"`     // Quotes that changed a price
+	Rejected         uint64     `json:"rejected" example:"1"`     // Quotes that were invalid or older than the current price
+	NotFound         uint64     `json:"not_found" example:"3"`    // Symbols the provider had no price for
+	Errors           uint64     `json:"errors" example:"2"`       // Failed quotes and database errors
+	StaleAssets      int64      `json:"stale_assets" example:"1"` // Assets marked stale after the last poll
+	LastPollAt       *time.Time `json:"last_poll_at" example:"2023-01-01T00:00:00Z"`
+	LastPollDuration string     `json:"last_poll_duration" example:"1.2s"`
+	LastError        string     `json:"last_error,omitempty" example:"prices.example.com returned 503 Service Unavailable"`
+}
+
+// Status describes the price feed of this instance
+type Status struct {
+	Enabled    bool     `json:"enabled" example:"true"`
+	Interval   string   `json:"interval,omitempty" example:"1m0s"`
+	StaleAfter string   `json:"stale_after,omitempty" example:"15m0s"`
+	Metrics    *Metrics `json:"metrics,omitempty"`
+}
+
+// metrics guards a worker's Metrics
+type metrics struct {
+	mu sync.Mutex
+	m  Metrics
+}
+
+func (m *metrics) update(fn func(*Metrics)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(&m.m)
+}
+
+func (m *metrics) snapshot() Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.m
+	if snapshot.LastPollAt != nil {
+		at := *snapshot.LastPollAt
+		snapshot.LastPollAt = &at
+	}
+	return snapshot
+}
@@ -0,0 +1,36 @@
+// Package pricefeed ingests asset prices from external sources.
+//
+// A PriceProvider quotes the price of an asset by its symbol. The Worker polls
+// the provider for every active asset on a fixed interval, writes new prices
+// to the asset and its price history, and marks prices that have not been
+// confirmed for longer than the configured age as stale. Built-in providers
+// read a local CSV or JSON file (FileProvider) or query an HTTP endpoint that
+// returns JSON (HTTPProvider).
+package pricefeed
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-api-test1/internal/money"
+)
+
+// ErrSymbolNotFound is returned by providers that have no price for a symbol
+var ErrSymbolNotFound = errors.New("symbol not found")
+
+// Quote is a price reported by a provider
+type Quote struct {
+	Price money.Decimal
+	// At is when the price was observed; providers without timestamps use the
+	// time the quote was fetched or their data was last modified
+	At time.Time
+}
+
+// PriceProvider quotes asset prices by symbol
+type PriceProvider interface {
+	// Name identifies the provider in logs and metrics
+	Name() string
+	// Quote returns the current price of symbol, or ErrSymbolNotFound
+	Quote(ctx context.Context, symbol string) (Quote, error)
+}
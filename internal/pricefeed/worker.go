@@ -0,0 +1,219 @@
+package pricefeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+	"go-api-test1/internal/prices"
+
+	"gorm.io/gorm"
+)
+
+// errRejected marks a quote that is not applied
+var errRejected = errors.New("quote rejected")
+
+// Config controls a Worker
+type Config struct {
+	// Interval is the time between polling rounds
+	Interval time.Duration
+	// StaleAfter is how old a price may get before the asset is marked stale
+	StaleAfter time.Duration
+	// RoundingMode is applied when quotes are rounded to an asset's price scale
+	RoundingMode money.RoundingMode
+	// Logger receives the worker's records; nil uses slog.Default()
+	Logger *slog.Logger
+}
+
+// Worker polls a PriceProvider for the prices of all active assets
+type Worker struct {
+	db       *gorm.DB
+	provider PriceProvider
+	cfg      Config
+	logger   *slog.Logger
+	metrics  metrics
+}
+
+// NewWorker creates a Worker; call Run to start polling
+func NewWorker(db *gorm.DB, provider PriceProvider, cfg Config) *Worker {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	w := &Worker{db: db, provider: provider, cfg: cfg, logger: logger.With("component", "price_feed")}
+	w.metrics.m.Provider = provider.Name()
+	return w
+}
+
+// Metrics returns a snapshot of the worker's metrics
+func (w *Worker) Metrics() Metrics {
+	return w.metrics.snapshot()
+}
+
+// Status returns the configuration and metrics of the worker. A nil worker
+// reports a disabled feed.
+func (w *Worker) Status() Status {
+	if w == nil {
+		return Status{}
+	}
+	metrics := w.Metrics()
+	return Status{
+		Enabled:    true,
+		Interval:   w.cfg.Interval.String(),
+		StaleAfter: w.cfg.StaleAfter.String(),
+		Metrics:    &metrics,
+	}
+}
+
+// Check reports whether the feed is fresh: a polling round has completed
+// within the last two intervals. A nil worker, a disabled feed, is fresh.
+func (w *Worker) Check(now time.Time) error {
+	if w == nil {
+		return nil
+	}
+	last := w.Metrics().LastPollAt
+	if last == nil {
+		return errors.New("no polling round has completed yet")
+	}
+	if age := now.Sub(*last); age > 2*w.cfg.Interval {
+		return fmt.Errorf("last polling round completed %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Run polls immediately and then every Interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.InfoContext(ctx, "Polling price provider", "provider", w.provider.Name(), "interval", w.cfg.Interval, "stale_after", w.cfg.StaleAfter)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.ErrorContext(ctx, "Poll failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			w.logger.InfoContext(ctx, "Stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs one round: it quotes every active asset, applies new prices and
+// marks the prices that are too old as stale. Failures of single quotes are
+// logged and counted but do not fail the round.
+func (w *Worker) Poll(ctx context.Context) error {
+	started := time.Now()
+	db := w.db.WithContext(ctx)
+
+	var assets []models.Asset
+	if err := db.Where("is_active = ?", true).Find(&assets).Error; err != nil {
+		w.recordError(err)
+		return err
+	}
+
+	updates := 0
+	for _, asset := range assets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		quote, err := w.provider.Quote(ctx, asset.Symbol)
+		if errors.Is(err, ErrSymbolNotFound) {
+			w.metrics.update(func(m *Metrics) { m.NotFound++ })
+			continue
+		}
+		if err != nil {
+			w.logger.WarnContext(ctx, "Failed to quote", "symbol", asset.Symbol, "error", err)
+			w.recordError(err)
+			continue
+		}
+		w.metrics.update(func(m *Metrics) { m.Quotes++ })
+
+		changed, err := w.apply(db, asset, quote)
+		switch {
+		case errors.Is(err, errRejected):
+			w.logger.InfoContext(ctx, "Rejected quote", "symbol", asset.Symbol, "price", quote.Price, "at", quote.At.Format(time.RFC3339))
+			w.metrics.update(func(m *Metrics) { m.Rejected++ })
+		case err != nil:
+			w.logger.ErrorContext(ctx, "Failed to update price", "symbol", asset.Symbol, "error", err)
+			w.recordError(err)
+		case changed:
+			updates++
+			w.metrics.update(func(m *Metrics) { m.Updates++ })
+		}
+	}
+
+	stale, err := w.markStale(db, time.Now())
+	if err != nil {
+		w.recordError(err)
+		return err
+	}
+
+	finished := time.Now()
+	w.metrics.update(func(m *Metrics) {
+		m.Polls++
+		m.StaleAssets = stale
+		m.LastPollAt = &finished
+		m.LastPollDuration = finished.Sub(started).String()
+	})
+	w.logger.InfoContext(ctx, "Polled assets", "assets", len(assets), "changed", updates, "stale", stale)
+	return nil
+}
+
+// apply writes a quote to the asset and reports whether the price changed.
+// Quotes older than the asset's current price are rejected, so a manual
+// price or a newer quote is never overwritten.
+func (w *Worker) apply(db *gorm.DB, asset models.Asset, quote Quote) (bool, error) {
+	price := quote.Price.Round(asset.PricingScale(), w.cfg.RoundingMode)
+	at := quote.At.UTC()
+	if !price.IsPositive() || (asset.PriceUpdatedAt != nil && at.Before(*asset.PriceUpdatedAt)) {
+		return false, errRejected
+	}
+
+	changed := !price.Equal(asset.Price)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The condition guards against a newer price written since the asset was loaded
+		result := tx.Model(&models.Asset{}).
+			Where("id = ? AND (price_updated_at IS NULL OR price_updated_at <= ?)", asset.ID, at).
+			Updates(map[string]interface{}{"price": price, "price_updated_at": at, "price_stale": false})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRejected
+		}
+		if !changed {
+			return nil
+		}
+		return prices.Record(tx, asset.ID, price, models.PriceSourceFeed, at)
+	})
+	return changed && err == nil, err
+}
+
+// markStale flags the prices last confirmed before StaleAfter and returns the
+// number of stale assets
+func (w *Worker) markStale(db *gorm.DB, now time.Time) (int64, error) {
+	cutoff := now.Add(-w.cfg.StaleAfter).UTC()
+	err := db.Model(&models.Asset{}).
+		Where("price_stale = ? AND (price_updated_at IS NULL OR price_updated_at < ?)", false, cutoff).
+		UpdateColumn("price_stale", true).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var stale int64
+	err = db.Model(&models.Asset{}).Where("price_stale = ?", true).Count(&stale).Error
+	return stale, err
+}
+
+func (w *Worker) recordError(err error) {
+	w.metrics.update(func(m *Metrics) {
+		m.Errors++
+		m.LastError = err.Error()
+	})
+}
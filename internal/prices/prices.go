@@ -0,0 +1,109 @@
+// Package prices records asset price history and aggregates it into candles.
+//
+// The history is append-only: every change of an asset's price adds an entry
+// and entries are never updated or deleted. Candles combine that history with
+// the buys and sells of the asset that completed in each interval. The
+// aggregation happens in Go rather than in SQL so that it is exact and works
+// the same on PostgreSQL and SQLite (which stores decimals as TEXT and has no
+// date_trunc).
+package prices
+
+import (
+	"sort"
+	"time"
+
+	"go-api-test1/internal/models"
+	"go-api-test1/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Intervals are the supported candle intervals
+var Intervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// MaxCandles bounds the number of intervals a single request may span
+const MaxCandles = 1000
+
+// Record appends a price to the asset's history
+func Record(tx *gorm.DB, assetID uint, price money.Decimal, source string, at time.Time) error {
+	return tx.Create(&models.AssetPrice{
+		AssetID:    assetID,
+		Price:      price,
+		Source:     source,
+		RecordedAt: at.UTC(),
+	}).Error
+}
+
+// tick is a price observation: a history entry or a completed trade
+type tick struct {
+	at     time.Time
+	price  money.Decimal
+	amount money.Decimal // zero for history entries
+	trade  bool
+}
+
+// Candles aggregates the asset's prices in [from, to) into candles of the
+// given interval. Intervals without any price or trade are omitted.
+func Candles(db *gorm.DB, assetID uint, interval time.Duration, from, to time.Time) ([]models.Candle, error) {
+	from, to = from.UTC(), to.UTC()
+
+	var history []models.AssetPrice
+	if err := db.Where("asset_id = ? AND recorded_at >= ? AND recorded_at < ?", assetID, from, to).
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	// A trade completes when its status changes to completed
+	var trades []struct {
+		Amount      money.Decimal
+		Price       money.Decimal
+		CompletedAt time.Time
+	}
+	if err := db.Table("transactions").
+		Select("transactions.amount, transactions.price, transaction_status_changes.created_at AS completed_at").
+		Joins("JOIN transaction_status_changes ON transaction_status_changes.transaction_id = transactions.id").
+		Where("transaction_status_changes.to_status = ?", models.TransactionStatusCompleted).
+		Where("transactions.asset_id = ? AND transactions.type IN ? AND transactions.deleted_at IS NULL",
+			assetID, []string{models.TransactionTypeBuy, models.TransactionTypeSell}).
+		Where("transaction_status_changes.created_at >= ? AND transaction_status_changes.created_at < ?", from, to).
+		Scan(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	ticks := make([]tick, 0, len(history)+len(trades))
+	for _, entry := range history {
+		ticks = append(ticks, tick{at: entry.RecordedAt, price: entry.Price})
+	}
+	for _, trade := range trades {
+		ticks = append(ticks, tick{at: trade.CompletedAt, price: trade.Price, amount: trade.Amount, trade: true})
+	}
+	sort.SliceStable(ticks, func(i, j int) bool { return ticks[i].at.Before(ticks[j].at) })
+
+	var candles []models.Candle
+	for _, t := range ticks {
+		// Truncation is relative to the zero time, so buckets align with UTC minutes, hours and days
+		start := t.at.UTC().Truncate(interval)
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(start) {
+			candles = append(candles, models.Candle{
+				Time: start, Open: t.price, High: t.price, Low: t.price, Volume: money.Zero,
+			})
+		}
+		candle := &candles[len(candles)-1]
+		if t.price.GreaterThan(candle.High) {
+			candle.High = t.price
+		}
+		if t.price.LessThan(candle.Low) {
+			candle.Low = t.price
+		}
+		candle.Close = t.price
+		if t.trade {
+			candle.Volume = candle.Volume.Add(t.amount)
+			candle.Trades++
+		}
+	}
+	return candles, nil
+}
@@ -0,0 +1,95 @@
+// Package tracing configures OpenTelemetry tracing.
+//
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. Server spans are created by middleware.Tracing and a child span
+// per database statement by the GORM Plugin, as long as queries run with the
+// request's context (db.WithContext(c.Request.Context())). Spans are exported over OTLP, written
+// to stdout, or not recorded at all.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// InstrumentationName names the tracer of this application
+const InstrumentationName = "go-api-test1"
+
+// Tracer returns the application's tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// NewExporter creates the span exporter called name. The OTLP exporter sends
+// spans over HTTP and is configured by the standard OTEL_EXPORTER_OTLP_*
+// environment variables. It returns nil for "none" and "".
+func NewExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected otlp, stdout or none", name)
+	}
+}
+
+// NewProvider creates a tracer provider exporting to exporter, sampling
+// ratio of the traces that do not have a sampled parent. Exporting is batched
+// unless sync is set, which tests use with an in-memory exporter.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string, ratio float64, sync bool) *sdktrace.TracerProvider {
+	export := sdktrace.WithBatcher(exporter)
+	if sync {
+		export = sdktrace.WithSyncer(exporter)
+	}
+	return sdktrace.NewTracerProvider(
+		export,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// Install makes provider the global tracer provider and W3C trace context and
+// baggage the global propagators. Incoming traceparent headers are honoured
+// even when provider is nil and spans are not recorded.
+func Install(provider trace.TracerProvider) {
+	if provider != nil {
+		otel.SetTracerProvider(provider)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup creates the exporter called name and installs a provider using it. The
+// returned function flushes and stops the provider.
+func Setup(ctx context.Context, name, serviceName string, ratio float64) (func(context.Context) error, error) {
+	exporter, err := NewExporter(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		Install(nil)
+		return func(context.Context) error { return nil }, nil
+	}
+	provider := NewProvider(exporter, serviceName, ratio, false)
+	Install(provider)
+	return provider.Shutdown, nil
+}
@@ -1,18 +1,32 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"go-api-test1/docs"
+	"go-api-test1/internal/auth"
 	"go-api-test1/internal/config"
 	"go-api-test1/internal/database"
 	"go-api-test1/internal/handlers"
+	"go-api-test1/internal/logging"
+	"go-api-test1/internal/metrics"
 	"go-api-test1/internal/middleware"
-	"go-api-test1/internal/models"
+	"go-api-test1/internal/pricefeed"
+	"go-api-test1/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	swaggerFiles "github.com/swaggo/files"
 	"gorm.io/gorm"
@@ -39,17 +53,53 @@ import (
 // @description Type "Bearer" followed by a space and JWT token.
 
 func main() {
-	log.Println("=== Go API Test1 Server Starting ===")
-	
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found - using environment variables")
+	envErr := godotenv.Load()
+
+	// Load configuration from the config file, environment and flags
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+
+	// Initialize logging
+	logger := logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	slog.SetDefault(logger)
+
+	logger.Info("=== Go API Test1 Server Starting ===")
+	if envErr != nil {
+		logger.Info("No .env file found - using environment variables")
 	} else {
-		log.Println("Environment variables loaded from .env file")
+		logger.Info("Environment variables loaded from .env file")
+	}
+
+	// `migrate` subcommands manage the schema and exit
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrateCommand(cfg, args[1:], logger); err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
+
+	// `promote-admin` grants an existing user the admin role and exits
+	if len(args) > 0 && args[0] == "promote-admin" {
+		if err := runPromoteAdminCommand(cfg, args[1:], logger); err != nil {
+			fatal("Promotion failed", err)
+		}
+		return
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("Failed to configure tracing", err)
 	}
 
 	// Initialize Swagger docs
-	log.Println("Initializing Swagger documentation...")
+	logger.Debug("Initializing Swagger documentation...")
 	docs.SwaggerInfo.Title = "Go API Test1"
 	docs.SwaggerInfo.Description = "A backend API with Users, Assets, and Transactions"
 	docs.SwaggerInfo.Version = "1.0"
@@ -57,237 +107,333 @@ func main() {
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	docs.SwaggerInfo.Schemes = []string{"http"}
 
-	// Initialize configuration
-	log.Println("Loading configuration...")
-	cfg := config.Load()
-	log.Printf("Configuration loaded - Database URL: %s", cfg.DatabaseURL)
-
-	// Initialize database
-	log.Println("Connecting to database...")
-	db, err := database.Initialize(cfg.DatabaseURL)
+	// Initialize database; the URL's password is redacted by the logger
+	logger.Info("Connecting to database...", "database_url", cfg.Database.URL)
+	db, err := database.Initialize(cfg.Database, logger)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
-	log.Println("Database connection established successfully")
+	logger.Info("Database connection established successfully")
 
-	// Auto-migrate the schema with error handling for existing data
-	log.Println("Running database migrations...")
+	// Metrics are served from their own registry on a separate listener
+	registry := metrics.NewRegistry()
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(registry, sqlDB, "main"); err != nil {
+			fatal("Failed to register connection pool metrics", err)
+		}
+	}
+
+	// Apply pending schema migrations
+	logger.Info("Running database migrations...")
 	if err := migrateDatabase(db); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		fatal("Failed to migrate database", err)
+	}
+
+	// Load token revocations
+	logger.Info("Loading token revocations...")
+	revocations := auth.NewRevocationStore(db)
+	if err := revocations.Load(); err != nil {
+		fatal("Failed to load token revocations", err)
+	}
+
+	// Start the price feed; it stops when workers is cancelled at shutdown
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var wg sync.WaitGroup
+	feed, err := newPriceFeed(db, cfg, logger)
+	if err != nil {
+		fatal("Failed to configure price feed", err)
+	}
+	if feed != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			feed.Run(workers)
+		}()
+	} else {
+		logger.Info("Price feed disabled - set PRICE_FEED_PROVIDER to enable it")
+	}
+	// Expired idempotency keys are deleted until shutdown too
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		middleware.PruneIdempotencyRecords(workers, db, logger)
+	}()
+
+	// Health probes check the database, migrations and price feed
+	migrator, err := newMigrator(db)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+	health := handlers.NewHealthHandler(db, migrator, feed, logger)
+
+	router := setupRouter(cfg, db, revocations, feed, health, logger)
+
+	// Start server
+	port := cfg.Server.Port
+	server := newServer(cfg.Server, router)
+
+	logger.Info("=== Server Configuration Complete ===")
+	logger.Info("Server starting", "port", port)
+	logger.Info("Swagger UI available", "url", fmt.Sprintf("http://localhost:%s/swagger/index.html", port))
+	logger.Info("API endpoints available", "url", fmt.Sprintf("http://localhost:%s/api/v1", port))
+	logger.Info("=== Server Ready to Accept Requests ===")
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	var metricsServer *http.Server
+	if cfg.Server.MetricsAddr != "" {
+		metricsServer = newMetricsServer(cfg.Server, registry)
+		logger.Info("Metrics available", "addr", cfg.Server.MetricsAddr, "path", "/metrics")
+		go func() {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+	} else {
+		logger.Info("Metrics listener disabled - set METRICS_ADDR to enable it")
+	}
+	select {
+	case err := <-serverErr:
+		fatal("Failed to start server", err)
+	case <-signals.Done():
+	}
+	// A second signal kills the process
+	stopSignals()
+
+	// Fail readiness first so load balancers stop sending requests, then
+	// let in-flight requests finish before stopping workers and the database
+	logger.Info("=== Shutting Down ===", "delay", cfg.Server.ShutdownDelay, "timeout", cfg.Server.ShutdownTimeout)
+	health.Drain()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Requests still running at the shutdown deadline were cut off", "error", err)
+	} else {
+		logger.Info("All requests finished")
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			logger.Error("Failed to stop metrics listener", "error", err)
+		}
+	}
+
+	stopWorkers()
+	wg.Wait()
+	logger.Info("Background workers stopped")
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database", "error", err)
+		}
+	}
+	logger.Info("=== Server Stopped ===")
+}
+
+// newServer creates the HTTP server with the configured timeouts and size
+// limits. Bodies larger than MaxBodyBytes fail to read, which handlers report
+// as invalid requests.
+func newServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           http.MaxBytesHandler(handler, cfg.MaxBodyBytes),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// newMetricsServer creates the listener serving the metrics of registry at
+// /metrics. It is separate from the API so that metrics are not exposed on the
+// public port.
+func newMetricsServer(cfg config.ServerConfig, registry *prometheus.Registry) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(registry))
+	return &http.Server{
+		Addr:              cfg.MetricsAddr,
+		Handler:           mux,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
+}
+
+// fatal logs a startup failure and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
 
+// setupRouter builds the Gin engine with middleware and all API routes
+func setupRouter(cfg *config.Config, db *gorm.DB, revocations *auth.RevocationStore, feed *pricefeed.Worker, health *handlers.HealthHandler, logger *slog.Logger) *gin.Engine {
 	// Initialize Gin router
-	log.Println("Initializing Gin router...")
-	router := gin.Default()
+	logger.Debug("Initializing Gin router...")
+	router := gin.New()
+	// Lets *gin.Context stand in for the request's context, which carries its span
+	router.ContextWithFallback = true
+	router.Use(gin.Recovery())
+
+	// Health probes are registered before the logging middleware so that
+	// frequent polling does not flood the logs
+	logger.Debug("Setting up health routes...")
+	router.GET("/healthz", health.Live)
+	router.GET("/readyz", health.Ready)
 
-	// Add logging middleware
-	log.Println("Adding logging middleware...")
-	router.Use(middleware.LoggerMiddleware())
+	// Add request ID, tracing and logging middleware
+	logger.Debug("Adding logging middleware...")
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Logger(logger))
+	router.Use(middleware.Metrics())
 
-	// Add CORS middleware
-	log.Println("Adding CORS middleware...")
-	router.Use(middleware.CORS())
+	// Add CORS and rate limiting middleware
+	logger.Debug("Adding CORS middleware...")
+	router.Use(middleware.CORS(cfg.CORS))
+	router.Use(middleware.RateLimit(cfg.RateLimit))
 
 	// Initialize handlers
-	log.Println("Initializing handlers...")
-	userHandler := handlers.NewUserHandler(db)
-	assetHandler := handlers.NewAssetHandler(db)
-	transactionHandler := handlers.NewTransactionHandler(db)
-	authHandler := handlers.NewAuthHandler(db)
-	log.Println("All handlers initialized successfully")
+	logger.Debug("Initializing handlers...")
+	userHandler := handlers.NewUserHandler(db, revocations, cfg, logger)
+	assetHandler := handlers.NewAssetHandler(db, cfg, logger)
+	transactionHandler := handlers.NewTransactionHandler(db, cfg, logger)
+	holdingHandler := handlers.NewHoldingHandler(db, logger)
+	priceHandler := handlers.NewPriceHandler(db, logger)
+	priceFeedHandler := handlers.NewPriceFeedHandler(feed, logger)
+	portfolioHandler := handlers.NewPortfolioHandler(db, cfg, logger)
+	reportHandler := handlers.NewReportHandler(db, cfg, logger)
+	authHandler := handlers.NewAuthHandler(db, revocations, cfg, logger)
+	logger.Debug("All handlers initialized successfully")
+	idempotency := middleware.Idempotency(db, cfg, logger)
+	authenticate := middleware.AuthMiddleware(cfg, revocations)
 
 	// API routes
-	log.Println("Setting up API routes...")
+	logger.Debug("Setting up API routes...")
 	v1 := router.Group("/api/v1")
 	{
 		// Authentication routes
-		log.Println("Setting up authentication routes...")
-		auth := v1.Group("/auth")
+		logger.Debug("Setting up authentication routes...")
+		authRoutes := v1.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			authRoutes.POST("/register", idempotency, authHandler.Register)
+			authRoutes.POST("/login", authHandler.Login)
+			authRoutes.POST("/refresh", authHandler.Refresh)
+			authRoutes.POST("/logout", authenticate, authHandler.Logout)
 		}
 
 		// Protected routes
-		log.Println("Setting up protected routes...")
+		logger.Debug("Setting up protected routes...")
 		protected := v1.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(authenticate)
 		{
 			// User routes
-			log.Println("Setting up user routes...")
+			logger.Debug("Setting up user routes...")
 			users := protected.Group("/users")
 			{
-				users.GET("", userHandler.GetUsers)
+				users.GET("", middleware.RequirePermission(auth.PermUsersManage), userHandler.GetUsers)
 				users.GET("/:id", userHandler.GetUser)
 				users.PUT("/:id", userHandler.UpdateUser)
 				users.DELETE("/:id", userHandler.DeleteUser)
+				users.GET("/:id/holdings", middleware.RequirePermission(auth.PermTransactionsRead), holdingHandler.GetUserHoldings)
+				users.GET("/:id/portfolio", middleware.RequirePermission(auth.PermTransactionsRead), portfolioHandler.GetUserPortfolio)
+				users.GET("/:id/reports/capital-gains", middleware.RequirePermission(auth.PermTransactionsRead), reportHandler.GetCapitalGains)
 			}
 
 			// Asset routes
-			log.Println("Setting up asset routes...")
+			logger.Debug("Setting up asset routes...")
 			assets := protected.Group("/assets")
 			{
-				assets.GET("", assetHandler.GetAssets)
-				assets.GET("/:id", assetHandler.GetAsset)
-				assets.POST("", assetHandler.CreateAsset)
-				assets.PUT("/:id", assetHandler.UpdateAsset)
-				assets.DELETE("/:id", assetHandler.DeleteAsset)
+				assets.GET("", middleware.RequirePermission(auth.PermAssetsRead), assetHandler.GetAssets)
+				assets.GET("/:id", middleware.RequirePermission(auth.PermAssetsRead), assetHandler.GetAsset)
+				assets.GET("/:id/prices", middleware.RequirePermission(auth.PermAssetsRead), priceHandler.GetPrices)
+				assets.GET("/:id/candles", middleware.RequirePermission(auth.PermAssetsRead), priceHandler.GetCandles)
+				assets.POST("", middleware.RequirePermission(auth.PermAssetsWrite), idempotency, assetHandler.CreateAsset)
+				assets.POST("/import", middleware.RequirePermission(auth.PermAssetsWrite), idempotency, assetHandler.ImportAssets)
+				assets.PUT("/:id", middleware.RequirePermission(auth.PermAssetsWrite), assetHandler.UpdateAsset)
+				assets.DELETE("/:id", middleware.RequirePermission(auth.PermAssetsWrite), assetHandler.DeleteAsset)
 			}
 
+			// Price feed routes
+			protected.GET("/price-feed", middleware.RequirePermission(auth.PermAssetsWrite), priceFeedHandler.GetStatus)
+
 			// Transaction routes
-			log.Println("Setting up transaction routes...")
+			logger.Debug("Setting up transaction routes...")
 			transactions := protected.Group("/transactions")
 			{
-				transactions.GET("", transactionHandler.GetTransactions)
-				transactions.GET("/:id", transactionHandler.GetTransaction)
-				transactions.POST("", transactionHandler.CreateTransaction)
-				transactions.PUT("/:id", transactionHandler.UpdateTransaction)
-				transactions.DELETE("/:id", transactionHandler.DeleteTransaction)
+				transactions.GET("", middleware.RequirePermission(auth.PermTransactionsRead), transactionHandler.GetTransactions)
+				transactions.GET("/export", middleware.RequirePermission(auth.PermTransactionsRead), transactionHandler.ExportTransactions)
+				transactions.GET("/:id", middleware.RequirePermission(auth.PermTransactionsRead), transactionHandler.GetTransaction)
+				transactions.POST("", middleware.RequirePermission(auth.PermTransactionsWrite), idempotency, transactionHandler.CreateTransaction)
+				transactions.PUT("/:id", middleware.RequirePermission(auth.PermTransactionsWrite), transactionHandler.UpdateTransaction)
+				transactions.DELETE("/:id", middleware.RequirePermission(auth.PermTransactionsWrite), transactionHandler.DeleteTransaction)
+				transactions.GET("/:id/history", middleware.RequirePermission(auth.PermTransactionsRead), transactionHandler.GetTransactionHistory)
+				transactions.POST("/:id/cancel", middleware.RequirePermission(auth.PermTransactionsWrite), transactionHandler.CancelTransaction)
+				transactions.POST("/:id/complete", middleware.RequirePermission(auth.PermTransactionsManage), transactionHandler.CompleteTransaction)
+				transactions.POST("/:id/fail", middleware.RequirePermission(auth.PermTransactionsManage), transactionHandler.FailTransaction)
 			}
 		}
 	}
 
 	// Swagger documentation
-	log.Println("Setting up Swagger documentation...")
+	logger.Debug("Setting up Swagger documentation...")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	return router
+}
 
-	log.Printf("=== Server Configuration Complete ===")
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Swagger UI available at: http://localhost:%s/swagger/index.html", port)
-	log.Printf("API endpoints available at: http://localhost:%s/api/v1", port)
-	log.Println("=== Server Ready to Accept Requests ===")
-	
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// newPriceFeed creates the price feed worker selected by the configuration, or
+// returns nil if the price feed is disabled
+func newPriceFeed(db *gorm.DB, cfg *config.Config, logger *slog.Logger) (*pricefeed.Worker, error) {
+	feedCfg := cfg.PriceFeed
+	var provider pricefeed.PriceProvider
+	switch feedCfg.Provider {
+	case "":
+		return nil, nil
+	case "file":
+		provider = pricefeed.NewFileProvider(feedCfg.File)
+	case "http":
+		httpProvider := pricefeed.NewHTTPProvider(feedCfg.URL, feedCfg.PriceField, feedCfg.TimeField, feedCfg.Timeout)
+		if feedCfg.APIKey != "" {
+			httpProvider.Header.Set("X-API-Key", feedCfg.APIKey)
+		}
+		provider = httpProvider
+	default:
+		return nil, fmt.Errorf("unknown PRICE_FEED_PROVIDER %q, expected file or http", feedCfg.Provider)
 	}
+	return pricefeed.NewWorker(db, provider, pricefeed.Config{
+		Interval:     feedCfg.Interval,
+		StaleAfter:   feedCfg.StaleAfter,
+		RoundingMode: cfg.RoundingMode,
+		Logger:       logger,
+	}), nil
 }
 
-// migrateDatabase handles database migration with proper error handling for existing data
+// migrateDatabase applies all pending schema migrations
 func migrateDatabase(db *gorm.DB) error {
-	// First, try to migrate without handling existing data
-	if err := db.AutoMigrate(&models.User{}, &models.Asset{}, &models.Transaction{}); err != nil {
-		log.Printf("Initial migration failed: %v", err)
-		
-		// Check if the error is related to username constraint
-		if contains(err.Error(), "username") && contains(err.Error(), "null values") {
-			log.Println("Detected username constraint issue. Attempting to fix existing data...")
-			
-			// First, add the username column as nullable
-			log.Println("Adding username column as nullable first...")
-			if err := db.Exec("ALTER TABLE users ADD COLUMN username text").Error; err != nil {
-				log.Printf("Error adding username column: %v", err)
-				// Column might already exist, continue
-			}
-			
-			// Check for users with null usernames using raw SQL
-			var nullUsernameCount int64
-			if err := db.Raw("SELECT COUNT(*) FROM users WHERE username IS NULL OR username = ''").Scan(&nullUsernameCount).Error; err != nil {
-				log.Printf("Error checking for null usernames: %v", err)
-				return err
-			}
-
-			if nullUsernameCount > 0 {
-				log.Printf("Found %d users with null/empty usernames. Updating them...", nullUsernameCount)
-				
-				// Update users with null usernames using raw SQL
-				result := db.Exec(`
-					UPDATE users 
-					SET username = SUBSTRING(email FROM 1 FOR POSITION('@' IN email) - 1) || '_' || id::text
-					WHERE username IS NULL OR username = ''
-				`)
-				
-				if result.Error != nil {
-					log.Printf("Error updating usernames: %v", result.Error)
-					return result.Error
-				}
-				
-				log.Printf("Updated %d users with generated usernames", result.RowsAffected)
-			}
-			
-			// Now add the NOT NULL and UNIQUE constraints
-			log.Println("Adding NOT NULL and UNIQUE constraints to username column...")
-			if err := db.Exec("ALTER TABLE users ALTER COLUMN username SET NOT NULL").Error; err != nil {
-				log.Printf("Error setting NOT NULL constraint: %v", err)
-				return err
-			}
-			
-			if err := db.Exec("CREATE UNIQUE INDEX idx_users_username ON users(username)").Error; err != nil {
-				log.Printf("Error creating unique index: %v", err)
-				// Index might already exist, continue
-			}
-			
-			log.Println("Username column migration completed successfully!")
-		} else if contains(err.Error(), "password") && contains(err.Error(), "null values") {
-			log.Println("Detected password constraint issue. Attempting to fix existing data...")
-			
-			// First, add the password column as nullable
-			log.Println("Adding password column as nullable first...")
-			if err := db.Exec("ALTER TABLE users ADD COLUMN password text").Error; err != nil {
-				log.Printf("Error adding password column: %v", err)
-				// Column might already exist, continue
-			}
-			
-			// Check for users with null passwords using raw SQL
-			var nullPasswordCount int64
-			if err := db.Raw("SELECT COUNT(*) FROM users WHERE password IS NULL OR password = ''").Scan(&nullPasswordCount).Error; err != nil {
-				log.Printf("Error checking for null passwords: %v", err)
-				return err
-			}
-
-			if nullPasswordCount > 0 {
-				log.Printf("Found %d users with null/empty passwords. Updating them with default password...", nullPasswordCount)
-				
-				// Update users with null passwords using raw SQL
-				// Note: In production, you should use proper password hashing
-				result := db.Exec(`
-					UPDATE users 
-					SET password = 'default_password_' || id::text
-					WHERE password IS NULL OR password = ''
-				`)
-				
-				if result.Error != nil {
-					log.Printf("Error updating passwords: %v", result.Error)
-					return result.Error
-				}
-				
-				log.Printf("Updated %d users with default passwords", result.RowsAffected)
-				log.Println("WARNING: Users with default passwords should change their passwords immediately!")
-			}
-			
-			// Now add the NOT NULL constraint
-			log.Println("Adding NOT NULL constraint to password column...")
-			if err := db.Exec("ALTER TABLE users ALTER COLUMN password SET NOT NULL").Error; err != nil {
-				log.Printf("Error setting NOT NULL constraint: %v", err)
-				return err
-			}
-			
-			log.Println("Password column migration completed successfully!")
-		} else {
-			// If it's not a known constraint issue, return the original error
-			return err
-		}
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background(), 0)
+	for _, mig := range applied {
+		slog.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+	}
+	if err != nil {
+		return err
 	}
 	
-	log.Println("Database migration completed successfully!")
+	slog.Info("Database migration completed successfully!")
 	return nil
 }
-
-// contains checks if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || (len(substr) > 0 && containsHelper(s, substr)))
-}
-
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
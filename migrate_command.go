@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"go-api-test1/internal/config"
+	"go-api-test1/internal/database"
+	"go-api-test1/internal/migrate"
+	"go-api-test1/migrations"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: go-api-test1 [flags] migrate <command> [n]
+
+commands:
+  up [n]    apply all pending migrations, or only the next n
+  down [n]  revert the last applied migration, or the last n
+  status    list migrations and whether they are applied
+  redo      revert and re-apply the last applied migration`
+
+// newMigrator creates a migrator for the embedded migrations of db's dialect
+func newMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, db.Dialector.Name(), migrations.FS)
+}
+
+// runMigrateCommand implements the `migrate` subcommands
+func runMigrateCommand(cfg *config.Config, args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+	n := 0
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q\n%s", args[1], migrateUsage)
+		}
+	}
+
+	db, err := database.Initialize(cfg.Database, logger)
+	if err != nil {
+		return err
+	}
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, n)
+		for _, mig := range applied {
+			fmt.Printf("applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, n)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("no migrations to revert")
+		}
+		return err
+	case "redo":
+		mig, err := migrator.Redo(ctx)
+		if err == nil && mig == nil {
+			fmt.Println("no migrations to redo")
+		} else if err == nil {
+			fmt.Printf("redone   %04d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "applied (modified)"
+			}
+			if s.Missing {
+				state = "applied (file missing)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+}
@@ -0,0 +1,13 @@
+// Package migrations embeds the versioned SQL migrations of the schema.
+//
+// Each dialect has its own directory of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Migrations are
+// applied by the internal/migrate package.
+package migrations
+
+import "embed"
+
+// FS holds the migration files, one directory per GORM dialect name
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS holdings;
+DROP TABLE IF EXISTS transaction_status_changes;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS assets;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,147 @@
+-- Baseline schema. Every statement is idempotent so that databases created
+-- by the former AutoMigrate start-up step can adopt versioned migrations. Their
+-- users, assets and transactions tables lack the columns added since and hold
+-- money in floating-point columns, so those columns are added and converted
+-- before any index refers to them.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    email text NOT NULL,
+    username text,
+    password text,
+    first_name text,
+    last_name text,
+    role text NOT NULL DEFAULT 'trader',
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+-- Databases from before usernames and passwords were required may lack them.
+-- Usernames are derived from the email address; accounts without a password
+-- are locked ('!' never matches a bcrypt hash) until an admin resets them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password text;
+UPDATE users
+SET username = split_part(email, '@', 1) || '_' || id::text
+WHERE username IS NULL OR username = '';
+UPDATE users SET password = '!' WHERE password IS NULL OR password = '';
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+ALTER TABLE users ALTER COLUMN password SET NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'trader';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS assets (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    symbol text NOT NULL,
+    type text NOT NULL,
+    description text,
+    price numeric(36,18) NOT NULL,
+    quantity_scale integer NOT NULL DEFAULT 8,
+    price_scale integer NOT NULL DEFAULT 2,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS quantity_scale integer NOT NULL DEFAULT 8;
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS price_scale integer NOT NULL DEFAULT 2;
+ALTER TABLE assets ALTER COLUMN price TYPE numeric(36,18) USING price::numeric(36,18);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assets_symbol ON assets (symbol);
+CREATE INDEX IF NOT EXISTS idx_assets_deleted_at ON assets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    asset_id bigint NOT NULL,
+    type text NOT NULL,
+    amount numeric(36,18) NOT NULL,
+    price numeric(36,18) NOT NULL,
+    total_value numeric(36,18) NOT NULL,
+    status text DEFAULT 'pending',
+    description text,
+    recipient_id bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_transactions_asset FOREIGN KEY (asset_id) REFERENCES assets (id)
+);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS recipient_id bigint;
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE numeric(36,18) USING amount::numeric(36,18),
+    ALTER COLUMN price TYPE numeric(36,18) USING price::numeric(36,18),
+    ALTER COLUMN total_value TYPE numeric(36,18) USING total_value::numeric(36,18);
+CREATE INDEX IF NOT EXISTS idx_transactions_recipient_id ON transactions (recipient_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON transactions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS transaction_status_changes (
+    id bigserial PRIMARY KEY,
+    transaction_id bigint NOT NULL,
+    from_status text,
+    to_status text NOT NULL,
+    changed_by_id bigint NOT NULL,
+    reason text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_status_changes_transaction_id ON transaction_status_changes (transaction_id);
+
+CREATE TABLE IF NOT EXISTS holdings (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    asset_id bigint NOT NULL,
+    quantity numeric(36,18) NOT NULL,
+    reserved numeric(36,18) NOT NULL,
+    version bigint NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_holdings_asset FOREIGN KEY (asset_id) REFERENCES assets (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holdings_user_asset ON holdings (user_id, asset_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    family_id text NOT NULL,
+    token_hash text NOT NULL,
+    device_name text,
+    expires_at timestamptz,
+    rotated_at timestamptz,
+    revoked_at timestamptz,
+    replaced_by_id bigint,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id bigserial PRIMARY KEY,
+    jti text,
+    user_id bigint NOT NULL,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_user_id ON revoked_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    key varchar(255) NOT NULL,
+    fingerprint text NOT NULL,
+    status_code bigint,
+    content_type text,
+    response_body bytea,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_user_key ON idempotency_records (user_id, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
@@ -0,0 +1,15 @@
+-- Append-only history of asset prices, seeded with the current prices.
+
+CREATE TABLE asset_prices (
+    id bigserial PRIMARY KEY,
+    asset_id bigint NOT NULL,
+    price numeric(36,18) NOT NULL,
+    source text NOT NULL,
+    recorded_at timestamptz NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_asset_prices_asset FOREIGN KEY (asset_id) REFERENCES assets (id)
+);
+CREATE INDEX idx_asset_prices_asset_recorded ON asset_prices (asset_id, recorded_at);
+
+INSERT INTO asset_prices (asset_id, price, source, recorded_at, created_at)
+SELECT id, price, 'initial', COALESCE(updated_at, now()), now() FROM assets WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE assets DROP COLUMN price_stale;
+ALTER TABLE assets DROP COLUMN price_updated_at;
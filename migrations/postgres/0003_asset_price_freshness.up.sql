@@ -0,0 +1,6 @@
+-- When each asset's price was last confirmed, and whether it is stale.
+
+ALTER TABLE assets ADD COLUMN price_updated_at timestamptz;
+ALTER TABLE assets ADD COLUMN price_stale boolean NOT NULL DEFAULT false;
+
+UPDATE assets SET price_updated_at = updated_at;
@@ -0,0 +1,181 @@
+-- Baseline schema. Databases created by the former AutoMigrate start-up step
+-- already have users, assets and transactions tables, but without the columns
+-- added since and with REAL money columns, which would round decimals written
+-- as text. Those tables are therefore created in their former shape if they do
+-- not exist, so that new and existing databases take the same path, and then
+-- rebuilt with the baseline columns, keeping their rows.
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `email` text NOT NULL,
+    `username` text NOT NULL,
+    `password` text NOT NULL,
+    `first_name` text,
+    `last_name` text,
+    `is_active` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime
+);
+
+CREATE TABLE IF NOT EXISTS `assets` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `symbol` text NOT NULL,
+    `type` text NOT NULL,
+    `description` text,
+    `price` real NOT NULL,
+    `is_active` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime
+);
+
+CREATE TABLE IF NOT EXISTS `transactions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `asset_id` integer NOT NULL,
+    `type` text NOT NULL,
+    `amount` real NOT NULL,
+    `price` real NOT NULL,
+    `total_value` real NOT NULL,
+    `status` text DEFAULT 'pending',
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    CONSTRAINT `fk_transactions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_transactions_asset` FOREIGN KEY (`asset_id`) REFERENCES `assets`(`id`)
+);
+
+CREATE TABLE `users_baseline` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `email` text NOT NULL,
+    `username` text NOT NULL,
+    `password` text NOT NULL,
+    `first_name` text,
+    `last_name` text,
+    `role` text NOT NULL DEFAULT 'trader',
+    `is_active` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime
+);
+INSERT INTO `users_baseline` (`id`, `email`, `username`, `password`, `first_name`, `last_name`, `is_active`, `created_at`, `updated_at`, `deleted_at`)
+SELECT `id`, `email`, `username`, `password`, `first_name`, `last_name`, `is_active`, `created_at`, `updated_at`, `deleted_at` FROM `users`;
+DROP TABLE `users`;
+ALTER TABLE `users_baseline` RENAME TO `users`;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users`(`email`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_username` ON `users`(`username`);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
+
+CREATE TABLE `assets_baseline` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `symbol` text NOT NULL,
+    `type` text NOT NULL,
+    `description` text,
+    `price` text NOT NULL,
+    `quantity_scale` integer NOT NULL DEFAULT 8,
+    `price_scale` integer NOT NULL DEFAULT 2,
+    `is_active` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime
+);
+INSERT INTO `assets_baseline` (`id`, `name`, `symbol`, `type`, `description`, `price`, `is_active`, `created_at`, `updated_at`, `deleted_at`)
+SELECT `id`, `name`, `symbol`, `type`, `description`, CAST(`price` AS TEXT), `is_active`, `created_at`, `updated_at`, `deleted_at` FROM `assets`;
+DROP TABLE `assets`;
+ALTER TABLE `assets_baseline` RENAME TO `assets`;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_assets_symbol` ON `assets`(`symbol`);
+CREATE INDEX IF NOT EXISTS `idx_assets_deleted_at` ON `assets`(`deleted_at`);
+
+CREATE TABLE `transactions_baseline` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `asset_id` integer NOT NULL,
+    `type` text NOT NULL,
+    `amount` text NOT NULL,
+    `price` text NOT NULL,
+    `total_value` text NOT NULL,
+    `status` text DEFAULT 'pending',
+    `description` text,
+    `recipient_id` integer,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    CONSTRAINT `fk_transactions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_transactions_asset` FOREIGN KEY (`asset_id`) REFERENCES `assets`(`id`)
+);
+INSERT INTO `transactions_baseline` (`id`, `user_id`, `asset_id`, `type`, `amount`, `price`, `total_value`, `status`, `description`, `created_at`, `updated_at`, `deleted_at`)
+SELECT `id`, `user_id`, `asset_id`, `type`, CAST(`amount` AS TEXT), CAST(`price` AS TEXT), CAST(`total_value` AS TEXT), `status`, `description`, `created_at`, `updated_at`, `deleted_at` FROM `transactions`;
+DROP TABLE `transactions`;
+ALTER TABLE `transactions_baseline` RENAME TO `transactions`;
+CREATE INDEX IF NOT EXISTS `idx_transactions_recipient_id` ON `transactions`(`recipient_id`);
+CREATE INDEX IF NOT EXISTS `idx_transactions_deleted_at` ON `transactions`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `transaction_status_changes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `transaction_id` integer NOT NULL,
+    `from_status` text,
+    `to_status` text NOT NULL,
+    `changed_by_id` integer NOT NULL,
+    `reason` text,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_transaction_status_changes_transaction_id` ON `transaction_status_changes`(`transaction_id`);
+
+CREATE TABLE IF NOT EXISTS `holdings` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `asset_id` integer NOT NULL,
+    `quantity` text NOT NULL,
+    `reserved` text NOT NULL,
+    `version` integer NOT NULL DEFAULT 0,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_holdings_asset` FOREIGN KEY (`asset_id`) REFERENCES `assets`(`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_holdings_user_asset` ON `holdings`(`user_id`, `asset_id`);
+
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `family_id` text NOT NULL,
+    `token_hash` text NOT NULL,
+    `device_name` text,
+    `expires_at` datetime,
+    `rotated_at` datetime,
+    `revoked_at` datetime,
+    `replaced_by_id` integer,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_refresh_tokens_token_hash` ON `refresh_tokens`(`token_hash`);
+CREATE INDEX IF NOT EXISTS `idx_refresh_tokens_family_id` ON `refresh_tokens`(`family_id`);
+CREATE INDEX IF NOT EXISTS `idx_refresh_tokens_user_id` ON `refresh_tokens`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `revoked_tokens` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `jti` text,
+    `user_id` integer NOT NULL,
+    `expires_at` datetime,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_revoked_tokens_jti` ON `revoked_tokens`(`jti`);
+CREATE INDEX IF NOT EXISTS `idx_revoked_tokens_user_id` ON `revoked_tokens`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_revoked_tokens_expires_at` ON `revoked_tokens`(`expires_at`);
+
+CREATE TABLE IF NOT EXISTS `idempotency_records` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `key` text NOT NULL,
+    `fingerprint` text NOT NULL,
+    `status_code` integer,
+    `content_type` text,
+    `response_body` blob,
+    `expires_at` datetime,
+    `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_idempotency_user_key` ON `idempotency_records`(`user_id`, `key`);
+CREATE INDEX IF NOT EXISTS `idx_idempotency_records_expires_at` ON `idempotency_records`(`expires_at`);
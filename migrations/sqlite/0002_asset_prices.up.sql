@@ -0,0 +1,15 @@
+-- Append-only history of asset prices, seeded with the current prices.
+
+CREATE TABLE `asset_prices` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `asset_id` integer NOT NULL,
+    `price` text NOT NULL,
+    `source` text NOT NULL,
+    `recorded_at` datetime NOT NULL,
+    `created_at` datetime,
+    CONSTRAINT `fk_asset_prices_asset` FOREIGN KEY (`asset_id`) REFERENCES `assets`(`id`)
+);
+CREATE INDEX `idx_asset_prices_asset_recorded` ON `asset_prices`(`asset_id`, `recorded_at`);
+
+INSERT INTO `asset_prices` (`asset_id`, `price`, `source`, `recorded_at`, `created_at`)
+SELECT `id`, `price`, 'initial', COALESCE(`updated_at`, CURRENT_TIMESTAMP), CURRENT_TIMESTAMP FROM `assets` WHERE `deleted_at` IS NULL;
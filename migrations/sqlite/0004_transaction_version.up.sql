@@ -0,0 +1,4 @@
+-- A counter bumped by every change of a transaction, so that concurrent
+-- edits can detect each other.
+
+ALTER TABLE `transactions` ADD COLUMN `version` integer NOT NULL DEFAULT 0;